@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/uptrace/bun"
+	"math/big"
 	"strconv"
 	"time"
 )
@@ -126,10 +127,121 @@ func SearchByWalletAddress(c *gin.Context, query *bun.SelectQuery) {
 		return
 	}
 
-	query = query.Where("wallet_address = ?", walletAddress)
+	query = database.WhereWallet(query, walletAddress)
 	return
 }
 
+func SearchByPrizeId(c *gin.Context, query *bun.SelectQuery) error {
+	strPrizeId, ok := c.GetQuery("prize_id")
+	if !ok {
+		return nil
+	}
+
+	prizeId, err := parseAmount(strPrizeId)
+	if err != nil {
+		return fmt.Errorf("error: invalid type value for prize_id, only integer type")
+	}
+
+	query = database.WherePrizeId(query, prizeId)
+	return nil
+}
+
+func SearchByContract(c *gin.Context, query *bun.SelectQuery) {
+	contract, ok := c.GetQuery("contract_address")
+	if !ok {
+		return
+	}
+
+	query = query.Where("contract_address = ?", contract)
+	return
+}
+
+func SearchByChain(c *gin.Context, query *bun.SelectQuery) error {
+	strChainId, ok := c.GetQuery("chain_id")
+	if !ok {
+		return nil
+	}
+
+	chainId, err := strconv.ParseInt(strChainId, 10, 64)
+	if err != nil {
+		return fmt.Errorf("error: invalid type value for chain_id, only integer type")
+	}
+
+	query = query.Where("chain_id = ?", chainId)
+	return nil
+}
+
+// juelsPerLink is the number of juels, the smallest unit of LINK, in a LINK.
+var juelsPerLink = new(big.Float).SetFloat64(1e18)
+
+func JuelsToLink(juels string) float64 {
+	value, ok := new(big.Float).SetString(juels)
+	if !ok {
+		return 0
+	}
+
+	link, _ := new(big.Float).Quo(value, juelsPerLink).Float64()
+	return link
+}
+
+// ProjectRunOut returns when a balance runs out if it keeps being spent at
+// the rate of cost per window, nil when nothing is spent.
+func ProjectRunOut(balance, cost string, window time.Duration) *time.Time {
+	spent, ok := new(big.Float).SetString(cost)
+	if !ok || spent.Sign() <= 0 {
+		return nil
+	}
+
+	left, ok := new(big.Float).SetString(balance)
+	if !ok {
+		return nil
+	}
+	if left.Sign() <= 0 {
+		now := time.Now()
+		return &now
+	}
+
+	windows, _ := new(big.Float).Quo(left, spent).Float64()
+	runOut := time.Now().Add(time.Duration(windows * float64(window)))
+	return &runOut
+}
+
+func BurnWindow(c *gin.Context) (time.Duration, error) {
+	days, err := strconv.Atoi(c.DefaultQuery("window_days", "7"))
+	if err != nil || days <= 0 {
+		return 0, fmt.Errorf("error: window_days must be a positive integer")
+	}
+
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+var latencyIntervals = []string{"hour", "day", "week", "month"}
+
+func LatencyInterval(c *gin.Context) (string, error) {
+	interval := c.DefaultQuery("interval", "day")
+	for _, value := range latencyIntervals {
+		if interval == value {
+			return interval, nil
+		}
+	}
+
+	return "", fmt.Errorf("error: invalid value for interval, only hour, day, week or month")
+}
+
+func GroupByChain(c *gin.Context, query *bun.SelectQuery) error {
+	groupBy, ok := c.GetQuery("group_by")
+	if !ok {
+		return nil
+	}
+
+	if groupBy == "chain" {
+		query = query.ColumnExpr("chain_id").GroupExpr("chain_id")
+		return nil
+	}
+
+	return fmt.Errorf("error: invalid value for group_by, only chain")
+}
+
 func SearchByTxHash(c *gin.Context, query *bun.SelectQuery) {
 	txHash, ok := c.GetQuery("transaction_hash")
 	if !ok {
@@ -140,6 +252,21 @@ func SearchByTxHash(c *gin.Context, query *bun.SelectQuery) {
 	return
 }
 
+func SearchByFinal(c *gin.Context, query *bun.SelectQuery) error {
+	strFinal, ok := c.GetQuery("final")
+	if !ok {
+		return nil
+	}
+
+	final, err := strconv.ParseBool(strFinal)
+	if err != nil {
+		return fmt.Errorf("error: invalid value for final, only true or false")
+	}
+
+	query = query.Where("final = ?", final)
+	return nil
+}
+
 func SortByAmount(c *gin.Context, query *bun.SelectQuery) error {
 	sort, ok := c.GetQuery("sort")
 	if !ok {
@@ -168,9 +295,9 @@ func SearchByTotalAmount(c *gin.Context, query *bun.SelectQuery) error {
 	}
 
 	if !isAmountFrom {
-		amountTo, err := strconv.ParseFloat(strAmountTo, 64)
+		amountTo, err := parseAmount(strAmountTo)
 		if err != nil {
-			return fmt.Errorf("error: invalid type value for amount_to, only float type")
+			return fmt.Errorf("error: invalid type value for amount_to, only integer type")
 		}
 
 		query = query.Having("sum(amount) <= ?", amountTo)
@@ -178,26 +305,26 @@ func SearchByTotalAmount(c *gin.Context, query *bun.SelectQuery) error {
 	}
 
 	if !isAmountTo {
-		amountFrom, err := strconv.ParseFloat(strAmountFrom, 64)
+		amountFrom, err := parseAmount(strAmountFrom)
 		if err != nil {
-			return fmt.Errorf("error: invalid type value for amount_from, only float type")
+			return fmt.Errorf("error: invalid type value for amount_from, only integer type")
 		}
 
 		query = query.Having("sum(amount) >= ?", amountFrom)
 		return nil
 	}
 
-	amountTo, err := strconv.ParseFloat(strAmountTo, 64)
+	amountTo, err := parseAmount(strAmountTo)
 	if err != nil {
-		return fmt.Errorf("error: invalid type value for amount_to, only float type")
+		return fmt.Errorf("error: invalid type value for amount_to, only integer type")
 	}
 
-	amountFrom, err := strconv.ParseFloat(strAmountFrom, 64)
+	amountFrom, err := parseAmount(strAmountFrom)
 	if err != nil {
-		return fmt.Errorf("error: invalid type value for amount_from, only float type")
+		return fmt.Errorf("error: invalid type value for amount_from, only integer type")
 	}
 
-	if amountFrom >= amountTo {
+	if amountFrom.Cmp(&amountTo.Int) >= 0 {
 		return fmt.Errorf("error: invalid value for amount_from and amount_to, amount_to must be greater than amount_from")
 	}
 
@@ -233,9 +360,9 @@ func SearchByAmount(c *gin.Context, query *bun.SelectQuery) error {
 	}
 
 	if !isAmountFrom {
-		amountTo, err := strconv.ParseFloat(strAmountTo, 64)
+		amountTo, err := parseAmount(strAmountTo)
 		if err != nil {
-			return fmt.Errorf("error: invalid type value for amount_to, only float type")
+			return fmt.Errorf("error: invalid type value for amount_to, only integer type")
 		}
 
 		query = query.Where("amount <= ?", amountTo)
@@ -243,26 +370,26 @@ func SearchByAmount(c *gin.Context, query *bun.SelectQuery) error {
 	}
 
 	if !isAmountTo {
-		amountFrom, err := strconv.ParseFloat(strAmountFrom, 64)
+		amountFrom, err := parseAmount(strAmountFrom)
 		if err != nil {
-			return fmt.Errorf("error: invalid type value for amount_from, only float type")
+			return fmt.Errorf("error: invalid type value for amount_from, only integer type")
 		}
 
 		query = query.Where("amount >= ?", amountFrom)
 		return nil
 	}
 
-	amountTo, err := strconv.ParseFloat(strAmountTo, 64)
+	amountTo, err := parseAmount(strAmountTo)
 	if err != nil {
-		return fmt.Errorf("error: invalid type value for amount_to, only float type")
+		return fmt.Errorf("error: invalid type value for amount_to, only integer type")
 	}
 
-	amountFrom, err := strconv.ParseFloat(strAmountFrom, 64)
+	amountFrom, err := parseAmount(strAmountFrom)
 	if err != nil {
-		return fmt.Errorf("error: invalid type value for amount_from, only float type")
+		return fmt.Errorf("error: invalid type value for amount_from, only integer type")
 	}
 
-	if amountFrom >= amountTo {
+	if amountFrom.Cmp(&amountTo.Int) >= 0 {
 		return fmt.Errorf("error: invalid value for amount_from and amount_to, amount_to must be greater than amount_from")
 	}
 
@@ -270,7 +397,17 @@ func SearchByAmount(c *gin.Context, query *bun.SelectQuery) error {
 	return nil
 }
 
-func PrizeIdArrayToPrize(prizeIdsArray [][]int) (int, float64) {
+func parseAmount(value string) (database.BigInt, error) {
+	var amount database.BigInt
+	_, ok := amount.SetString(value, 10)
+	if !ok {
+		return amount, fmt.Errorf("error: invalid integer %q", value)
+	}
+
+	return amount, nil
+}
+
+func PrizeIdArrayToPrize(prizeIdsArray [][]database.BigInt) (int, float64) {
 	var ticket int
 	var token float64
 	for _, prizeIds := range prizeIdsArray {
@@ -281,16 +418,18 @@ func PrizeIdArrayToPrize(prizeIdsArray [][]int) (int, float64) {
 
 var prizes = []float64{0, 0.1, 1, 0.25, 2, 0.5, 0.15, 2.5}
 
-func PrizeIdToPrize(prizeIds []int, ticket *int, token *float64) {
+func PrizeIdToPrize(prizeIds []database.BigInt, ticket *int, token *float64) {
 	for _, prizeId := range prizeIds {
-		if prizeId == 0 {
+		// ids without a prize, including 0, are skipped
+		if !prizeId.IsInt64() || prizeId.Int64() <= 0 || prizeId.Int64() >= int64(len(prizes)) {
 			continue
 		}
-		if prizeId == 2 || prizeId == 4 {
-			*ticket = *ticket + int(prizes[prizeId])
+		id := prizeId.Int64()
+		if id == 2 || id == 4 {
+			*ticket = *ticket + int(prizes[id])
 			continue
 		}
-		*token = *token + prizes[prizeId]
+		*token = *token + prizes[id]
 	}
 
 	// math.Round()
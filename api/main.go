@@ -1,6 +1,7 @@
 package api
 
 import (
+	"VRFChainlink/event"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/uptrace/bun"
@@ -10,6 +11,10 @@ import (
 
 var db *bun.DB
 
+// pools are the RPC pools of the trackers running in the process, reported by
+// GET /metrics.
+var pools []*event.RpcPool
+
 type GinEngine struct {
 	g *gin.Engine
 }
@@ -19,6 +24,11 @@ func NewGin(database *bun.DB) *GinEngine {
 	return &GinEngine{g: gin.New()}
 }
 
+// AddPool reports the health and the rate limit of pool in GET /metrics.
+func (gin *GinEngine) AddPool(pool *event.RpcPool) {
+	pools = append(pools, pool)
+}
+
 func (gin *GinEngine) Run() {
 	gin.SetupRoutes()
 	err := gin.g.Run(fmt.Sprintf(":%s", os.Getenv("PORT_SV")))
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"VRFChainlink/event"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"net/http"
+)
+
+// RpcMetrics are the health of the endpoints of a chain and the usage of its
+// rate limit, which is nil when the chain is not rate limited.
+type RpcMetrics struct {
+	ChainId   int64                  `json:"chain_id"`
+	Endpoints []event.EndpointStatus `json:"endpoints"`
+	Limiter   *event.LimiterMetrics  `json:"limiter"`
+}
+
+func GetMetrics(c *gin.Context) {
+	metrics := []RpcMetrics{}
+	for _, pool := range pools {
+		chain := RpcMetrics{
+			ChainId:   pool.ChainId,
+			Endpoints: pool.Status(),
+		}
+		if pool.Limiter != nil {
+			limiter := pool.Limiter.Metrics()
+			chain.Limiter = &limiter
+		}
+
+		metrics = append(metrics, chain)
+	}
+
+	c.JSON(http.StatusOK, render.JSON{Data: metrics})
+	return
+}
@@ -14,9 +14,19 @@ import (
 func GetRequestRandomById(c *gin.Context) {
 	responseData := new(database.RequestRandom)
 	requestId := c.Param("request_id")
-	err := db.NewSelect().Model(responseData).
-		Where("request_id = ?", requestId).
-		Scan(context.Background())
+	query := db.NewSelect().Model(responseData).
+		Where("request_id = ?", requestId)
+
+	SearchByContract(c, query)
+
+	err := SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = query.Scan(context.Background())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
 		fmt.Println(err)
@@ -30,9 +40,19 @@ func GetRequestRandomById(c *gin.Context) {
 func GetResponseRandomById(c *gin.Context) {
 	responseData := new(database.ResponseRandom)
 	requestId := c.Param("request_id")
-	err := db.NewSelect().Model(responseData).
-		Where("request_id = ?", requestId).
-		Scan(context.Background())
+	query := db.NewSelect().Model(responseData).
+		Where("request_id = ?", requestId)
+
+	SearchByContract(c, query)
+
+	err := SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = query.Scan(context.Background())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
 		fmt.Println(err)
@@ -76,6 +96,22 @@ func GetRequestRandom(c *gin.Context) {
 		return
 	}
 
+	err = SearchByFinal(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	SearchByContract(c, query)
+
+	err = SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
 	err = SearchByTime(c, query)
 	if err != nil {
 		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
@@ -113,6 +149,22 @@ func GetResponseRandom(c *gin.Context) {
 	SearchByWalletAddress(c, query)
 	SearchByTxHash(c, query)
 
+	err = SearchByFinal(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	SearchByContract(c, query)
+
+	err = SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
 	err = SearchByTime(c, query)
 	if err != nil {
 		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
@@ -141,8 +193,9 @@ func GetTotalSpinning(c *gin.Context) {
 	}
 
 	type Spinning struct {
-		WalletAddress string `json:"wallet_address"`
-		TotalAmount   int    `json:"total_amount"`
+		WalletAddress string          `json:"wallet_address"`
+		ChainId       int64           `json:"chain_id,omitempty"`
+		TotalAmount   database.BigInt `json:"total_amount"`
 	}
 
 	var spin []Spinning
@@ -155,6 +208,13 @@ func GetTotalSpinning(c *gin.Context) {
 		Limit(pageFilter.Size).
 		Offset(offset)
 
+	err = GroupByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
 	err = SortByTotalAmount(c, query)
 	if err != nil {
 		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
@@ -169,6 +229,15 @@ func GetTotalSpinning(c *gin.Context) {
 		return
 	}
 
+	SearchByContract(c, query)
+
+	err = SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
 	err = SearchByTime(c, query)
 	if err != nil {
 		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
@@ -189,14 +258,23 @@ func GetTotalSpinning(c *gin.Context) {
 
 func GetSpinningCountByAddress(c *gin.Context) {
 	responseData := new(database.RequestRandom)
-	amount := new(int)
+	amount := new(database.BigInt)
 	address := c.Param("address")
 
 	query := db.NewSelect().Model(responseData).
-		ColumnExpr("sum(amount)").
-		Where("wallet_address = ?", address)
+		ColumnExpr("sum(amount)")
+	query = database.WhereWallet(query, address)
+
+	SearchByContract(c, query)
+
+	err := SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
 
-	err := SearchByTime(c, query)
+	err = SearchByTime(c, query)
 	if err != nil {
 		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
 		fmt.Println(err)
@@ -260,17 +338,26 @@ func GetSpinningTotalPrizeByAddress(c *gin.Context) {
 	address := c.Param("address")
 	query := db.NewSelect().Model(new(database.ResponseRandom)).
 		ColumnExpr("json_agg(prize_ids)").
-		Where("wallet_address = ?", address).
-		GroupExpr("wallet_address")
+		GroupExpr("lower(wallet_address)")
+	query = database.WhereWallet(query, address)
+
+	SearchByContract(c, query)
 
-	err := SearchByTime(c, query)
+	err := SearchByChain(c, query)
 	if err != nil {
 		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
 		fmt.Println(err)
 		return
 	}
 
-	var prize [][][]int
+	err = SearchByTime(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	var prize [][][]database.BigInt
 	err = query.Scan(context.Background(), &prize)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
@@ -297,7 +384,8 @@ func GetSpinningTotalPrizeByAddress(c *gin.Context) {
 func GetSpinningTotalPrize(c *gin.Context) {
 	type Prize struct {
 		WalletAddress string `json:"wallet_address"`
-		Prize         [][]int
+		ChainId       int64  `json:"chain_id"`
+		Prize         [][]database.BigInt
 	}
 
 	pageFilter := new(PageFilter)
@@ -316,7 +404,22 @@ func GetSpinningTotalPrize(c *gin.Context) {
 		Limit(pageFilter.Size).
 		Offset(offset)
 
+	err = GroupByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
 	SearchByTxHash(c, query)
+	SearchByContract(c, query)
+
+	err = SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
 
 	err = SearchByTime(c, query)
 	if err != nil {
@@ -335,6 +438,7 @@ func GetSpinningTotalPrize(c *gin.Context) {
 
 	type TotalPrize struct {
 		Address string  `json:"address"`
+		ChainId int64   `json:"chain_id,omitempty"`
 		Ticket  int     `json:"ticket"`
 		Token   float64 `json:"token"`
 	}
@@ -343,6 +447,7 @@ func GetSpinningTotalPrize(c *gin.Context) {
 		ticket, token := PrizeIdArrayToPrize(prize.Prize)
 		totalPrize = append(totalPrize, TotalPrize{
 			Address: prize.WalletAddress,
+			ChainId: prize.ChainId,
 			Ticket:  ticket,
 			Token:   token,
 		})
@@ -371,6 +476,29 @@ func GetSpinningPrize(c *gin.Context) {
 	SearchByWalletAddress(c, query)
 	SearchByTxHash(c, query)
 
+	err = SearchByPrizeId(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = SearchByFinal(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	SearchByContract(c, query)
+
+	err = SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
 	err = SearchByTime(c, query)
 	if err != nil {
 		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
@@ -390,6 +518,7 @@ func GetSpinningPrize(c *gin.Context) {
 		RequestId       string    `json:"request_id"`
 		TransactionHash string    `json:"transaction_hash"`
 		Index           int       `json:"index"`
+		Final           bool      `json:"final"`
 		Time            time.Time `json:"time"`
 		Ticket          int       `json:"ticket"`
 		Token           float64   `json:"token"`
@@ -406,6 +535,7 @@ func GetSpinningPrize(c *gin.Context) {
 			RequestId:       dt.RequestId,
 			TransactionHash: dt.TxHash,
 			Index:           dt.Index,
+			Final:           dt.Final,
 			Time:            dt.Time,
 			Ticket:          ticket,
 			Token:           token,
@@ -420,10 +550,19 @@ func GetSpinningPrizeById(c *gin.Context) {
 	data := new(database.ResponseRandom)
 	requestId := c.Param("request_id")
 
-	err := db.NewSelect().Model(data).
-		Where("request_id = ?", requestId).
-		Scan(context.Background())
+	query := db.NewSelect().Model(data).
+		Where("request_id = ?", requestId)
+
+	SearchByContract(c, query)
 
+	err := SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = query.Scan(context.Background())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
 		fmt.Println(err)
@@ -435,6 +574,7 @@ func GetSpinningPrizeById(c *gin.Context) {
 		RequestId       string    `json:"request_id"`
 		TransactionHash string    `json:"transaction_hash"`
 		Index           int       `json:"index"`
+		Final           bool      `json:"final"`
 		Time            time.Time `json:"time"`
 		Ticket          int       `json:"ticket"`
 		Token           float64   `json:"token"`
@@ -451,6 +591,7 @@ func GetSpinningPrizeById(c *gin.Context) {
 		RequestId:       data.RequestId,
 		TransactionHash: data.TxHash,
 		Index:           data.Index,
+		Final:           data.Final,
 		Time:            data.Time,
 		Ticket:          ticket,
 		Token:           token,
@@ -458,3 +599,473 @@ func GetSpinningPrizeById(c *gin.Context) {
 
 	return
 }
+
+func GetVrfById(c *gin.Context) {
+	requestData := new(database.VrfRequest)
+	var fulfillData []database.VrfFulfillment
+	var verifyData []database.VrfVerification
+	requestId := c.Param("request_id")
+	query := db.NewSelect().Model(requestData).
+		Where("request_id = ?", requestId)
+	fulfillQuery := db.NewSelect().Model(&fulfillData).
+		Where("request_id = ?", requestId)
+	verifyQuery := db.NewSelect().Model(&verifyData).
+		Where("request_id = ?", requestId)
+
+	for _, q := range []*bun.SelectQuery{query, fulfillQuery, verifyQuery} {
+		SearchByContract(c, q)
+
+		err := SearchByChain(c, q)
+		if err != nil {
+			c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+			fmt.Println(err)
+			return
+		}
+	}
+
+	err := query.Scan(context.Background())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = fulfillQuery.Scan(context.Background())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = verifyQuery.Scan(context.Background())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	type VrfDetail struct {
+		Request      *database.VrfRequest      `json:"request"`
+		Fulfillment  *database.VrfFulfillment  `json:"fulfillment"`
+		Verification *database.VrfVerification `json:"verification"`
+	}
+
+	detail := VrfDetail{Request: requestData}
+	if len(fulfillData) > 0 {
+		detail.Fulfillment = &fulfillData[0]
+	}
+	if len(verifyData) > 0 {
+		detail.Verification = &verifyData[0]
+	}
+
+	c.JSON(http.StatusOK, render.JSON{Data: detail})
+	return
+}
+
+func GetFailedRandom(c *gin.Context) {
+	responseData := new([]database.FailedRandom)
+
+	pageFilter := new(PageFilter)
+	err := pageFilter.Check(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	offset := (pageFilter.Page - 1) * pageFilter.Size
+	query := db.NewSelect().Model(responseData).
+		Order("block_number DESC").
+		Limit(pageFilter.Size).
+		Offset(offset)
+
+	SearchByWalletAddress(c, query)
+	SearchByTxHash(c, query)
+
+	err = SearchByFinal(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	SearchByContract(c, query)
+
+	err = SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = SearchByTime(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = query.Scan(context.Background())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, render.JSON{Data: responseData})
+	return
+}
+
+func GetPrizeMismatch(c *gin.Context) {
+	responseData := new([]database.PrizeCheck)
+
+	pageFilter := new(PageFilter)
+	err := pageFilter.Check(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	offset := (pageFilter.Page - 1) * pageFilter.Size
+	query := db.NewSelect().Model(responseData).
+		Where("matched = ?", false).
+		Order("block_number DESC").
+		Limit(pageFilter.Size).
+		Offset(offset)
+
+	SearchByContract(c, query)
+
+	err = SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = query.Scan(context.Background())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, render.JSON{Data: responseData})
+	return
+}
+
+func GetSpins(c *gin.Context) {
+	responseData := new([]database.Spin)
+
+	pageFilter := new(PageFilter)
+	err := pageFilter.Check(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	offset := (pageFilter.Page - 1) * pageFilter.Size
+	query := db.NewSelect().Model(responseData).
+		Order("block_number DESC").
+		Limit(pageFilter.Size).
+		Offset(offset)
+
+	SearchByWalletAddress(c, query)
+
+	err = SearchByFinal(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	SearchByContract(c, query)
+
+	err = SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = SearchByTime(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = query.Scan(context.Background())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, render.JSON{Data: responseData})
+	return
+}
+
+func GetSpinLatency(c *gin.Context) {
+	interval, err := LatencyInterval(c)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	query := db.NewSelect().Model((*database.Spin)(nil)).
+		ColumnExpr("date_trunc(?, time) AS period", interval).
+		ColumnExpr("count(*) AS spins").
+		ColumnExpr("percentile_cont(0.5) WITHIN GROUP (ORDER BY latency_seconds) AS p50_seconds").
+		ColumnExpr("percentile_cont(0.9) WITHIN GROUP (ORDER BY latency_seconds) AS p90_seconds").
+		ColumnExpr("percentile_cont(0.99) WITHIN GROUP (ORDER BY latency_seconds) AS p99_seconds").
+		ColumnExpr("percentile_cont(0.5) WITHIN GROUP (ORDER BY latency_blocks) AS p50_blocks").
+		ColumnExpr("percentile_cont(0.9) WITHIN GROUP (ORDER BY latency_blocks) AS p90_blocks").
+		ColumnExpr("percentile_cont(0.99) WITHIN GROUP (ORDER BY latency_blocks) AS p99_blocks").
+		GroupExpr("period").
+		OrderExpr("period ASC")
+
+	SearchByContract(c, query)
+
+	err = SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = SearchByTime(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	type Latency struct {
+		Period     time.Time `bun:"period" json:"period"`
+		Spins      int       `bun:"spins" json:"spins"`
+		P50Seconds float64   `bun:"p50_seconds" json:"p50_seconds"`
+		P90Seconds float64   `bun:"p90_seconds" json:"p90_seconds"`
+		P99Seconds float64   `bun:"p99_seconds" json:"p99_seconds"`
+		P50Blocks  float64   `bun:"p50_blocks" json:"p50_blocks"`
+		P90Blocks  float64   `bun:"p90_blocks" json:"p90_blocks"`
+		P99Blocks  float64   `bun:"p99_blocks" json:"p99_blocks"`
+	}
+
+	var latency []Latency
+	err = query.Scan(context.Background(), &latency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, render.JSON{Data: latency})
+	return
+}
+
+func GetPendingRandom(c *gin.Context) {
+	responseData := new([]database.PendingRequest)
+
+	pageFilter := new(PageFilter)
+	err := pageFilter.Check(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	offset := (pageFilter.Page - 1) * pageFilter.Size
+	query := database.PendingRequestQuery(db, responseData).
+		Order("block_number ASC").
+		Limit(pageFilter.Size).
+		Offset(offset)
+
+	SearchByWalletAddress(c, query)
+	SearchByContract(c, query)
+
+	err = SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = SearchByTime(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = query.Scan(context.Background())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, render.JSON{Data: responseData})
+	return
+}
+
+func GetSubscription(c *gin.Context) {
+	window, err := BurnWindow(c)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	type Subscription struct {
+		ChainId     int64    `bun:"chain_id" json:"chain_id"`
+		SubId       uint64   `bun:"sub_id" json:"sub_id"`
+		Contracts   []string `bun:"contracts,array" json:"contracts"`
+		Balance     string   `bun:"balance" json:"balance"`
+		Spins       int      `bun:"spins" json:"spins"`
+		TotalCost   string   `bun:"total_cost" json:"total_cost"`
+		CostPerSpin string   `bun:"cost_per_spin" json:"cost_per_spin"`
+		RecentCost  string   `bun:"recent_cost" json:"recent_cost"`
+	}
+
+	query := db.NewSelect().TableExpr("(?) AS sub", database.SubscriptionLedgerQuery(db)).
+		ColumnExpr("chain_id, sub_id").
+		ColumnExpr("array_agg(DISTINCT contract_address) AS contracts").
+		ColumnExpr("(array_agg(balance ORDER BY block_number DESC, index DESC))[1] AS balance").
+		ColumnExpr("count(*) FILTER (WHERE kind = ?) AS spins", database.SubscriptionPayment).
+		ColumnExpr("coalesce(sum(amount) FILTER (WHERE kind = ?), 0) AS total_cost", database.SubscriptionPayment).
+		ColumnExpr("coalesce(round(avg(amount) FILTER (WHERE kind = ?)), 0) AS cost_per_spin", database.SubscriptionPayment).
+		ColumnExpr("coalesce(sum(amount) FILTER (WHERE kind = ? AND time >= ?), 0) AS recent_cost", database.SubscriptionPayment, time.Now().Add(-window)).
+		GroupExpr("chain_id, sub_id")
+
+	// a subscription is shared by its contracts, the filter keeps the ones
+	// the contract recorded entries of
+	contract, ok := c.GetQuery("contract_address")
+	if ok {
+		query = query.Having("bool_or(contract_address = ?)", contract)
+	}
+
+	err = SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	var subscriptions []Subscription
+	err = query.Scan(context.Background(), &subscriptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	type SubscriptionDetail struct {
+		Subscription
+		BalanceLink     float64    `json:"balance_link"`
+		CostPerSpinLink float64    `json:"cost_per_spin_link"`
+		BurnPerDayLink  float64    `json:"burn_per_day_link"`
+		RunOut          *time.Time `json:"run_out"`
+		Consumers       []string   `json:"consumers"`
+	}
+
+	var details []SubscriptionDetail
+	for _, subscription := range subscriptions {
+		var consumerEvents []database.SubscriptionEvent
+		err = db.NewSelect().Model(&consumerEvents).
+			Where("chain_id = ?", subscription.ChainId).
+			Where("sub_id = ?", subscription.SubId).
+			Where("kind IN (?)", bun.In([]string{database.SubscriptionConsumerAdded, database.SubscriptionConsumerRemoved})).
+			Order("block_number ASC", "index ASC").
+			Scan(context.Background())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+			fmt.Println(err)
+			return
+		}
+
+		consumers := []string{}
+		for _, event := range consumerEvents {
+			if event.Kind == database.SubscriptionConsumerAdded {
+				consumers = append(consumers, event.Consumer)
+				continue
+			}
+			for i, consumer := range consumers {
+				if consumer == event.Consumer {
+					consumers = append(consumers[:i], consumers[i+1:]...)
+					break
+				}
+			}
+		}
+
+		details = append(details, SubscriptionDetail{
+			Subscription:    subscription,
+			BalanceLink:     JuelsToLink(subscription.Balance),
+			CostPerSpinLink: JuelsToLink(subscription.CostPerSpin),
+			BurnPerDayLink:  JuelsToLink(subscription.RecentCost) / window.Hours() * 24,
+			RunOut:          ProjectRunOut(subscription.Balance, subscription.RecentCost, window),
+			Consumers:       consumers,
+		})
+	}
+
+	c.JSON(http.StatusOK, render.JSON{Data: details})
+	return
+}
+
+func GetSubscriptionEvents(c *gin.Context) {
+	responseData := new([]database.SubscriptionEvent)
+
+	pageFilter := new(PageFilter)
+	err := pageFilter.Check(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	offset := (pageFilter.Page - 1) * pageFilter.Size
+	query := db.NewSelect().Model(responseData).
+		ModelTableExpr("(?) AS sub", database.SubscriptionLedgerQuery(db)).
+		ColumnExpr("sub.*").
+		Order("block_number DESC", "index DESC").
+		Limit(pageFilter.Size).
+		Offset(offset)
+
+	subId, ok := c.GetQuery("sub_id")
+	if ok {
+		query = query.Where("sub_id = ?", subId)
+	}
+
+	kind, ok := c.GetQuery("kind")
+	if ok {
+		query = query.Where("kind = ?", kind)
+	}
+
+	SearchByContract(c, query)
+
+	err = SearchByChain(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = SearchByTime(c, query)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	err = query.Scan(context.Background())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, render.JSON{Data: fmt.Sprintf("%s", err)})
+		fmt.Println(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, render.JSON{Data: responseData})
+	return
+}
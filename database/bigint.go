@@ -0,0 +1,73 @@
+package database
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// BigInt is an arbitrary-precision integer, for the uint256 values of the
+// contract. It is stored in NUMERIC columns and written to JSON as a string,
+// JSON numbers are read as well so that json_agg results can be scanned.
+type BigInt struct {
+	big.Int
+}
+
+func NewBigInt(x *big.Int) BigInt {
+	var i BigInt
+	i.Set(x)
+	return i
+}
+
+func BigInts(xs []*big.Int) []BigInt {
+	ints := make([]BigInt, len(xs))
+	for i, x := range xs {
+		ints[i] = NewBigInt(x)
+	}
+
+	return ints
+}
+
+func (i BigInt) Value() (driver.Value, error) {
+	return i.String(), nil
+}
+
+func (i *BigInt) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		i.SetInt64(0)
+		return nil
+	case int64:
+		i.SetInt64(src)
+		return nil
+	case []byte:
+		return i.setString(string(src))
+	case string:
+		return i.setString(src)
+	}
+
+	return fmt.Errorf("database: cannot scan %T into BigInt", src)
+}
+
+func (i BigInt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+func (i *BigInt) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	return i.setString(string(bytes.Trim(data, `"`)))
+}
+
+func (i *BigInt) setString(value string) error {
+	_, ok := i.SetString(value, 10)
+	if !ok {
+		return fmt.Errorf("database: invalid integer %q", value)
+	}
+
+	return nil
+}
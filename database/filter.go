@@ -0,0 +1,17 @@
+package database
+
+import (
+	"github.com/uptrace/bun"
+)
+
+// WhereWallet filters a query on a wallet address whatever its case, in the
+// form the lower(wallet_address) indexes are built for.
+func WhereWallet(query *bun.SelectQuery, wallet string) *bun.SelectQuery {
+	return query.Where("lower(wallet_address) = lower(?)", wallet)
+}
+
+// WherePrizeId filters a query of responses on the ones that won prizeId,
+// with the containment operator the GIN index on prize_ids supports.
+func WherePrizeId(query *bun.SelectQuery, prizeId BigInt) *bun.SelectQuery {
+	return query.Where("prize_ids @> ARRAY[?]::numeric[]", prizeId)
+}
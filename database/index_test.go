@@ -0,0 +1,183 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSchema connects to a schema of its own in the Postgres database of
+// TEST_DATABASE_DSN and skips the test when there is none. Sequential scans
+// are disabled, so that the plans of the empty tables show an index whenever
+// it is usable.
+func testSchema(t *testing.T) *bun.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	setup := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn))), pgdialect.New())
+	defer setup.Close()
+
+	schema := fmt.Sprintf("index_test_%d", time.Now().UnixNano())
+	_, err := setup.ExecContext(context.Background(), "CREATE SCHEMA ?", bun.Ident(schema))
+	if err != nil {
+		t.Skipf("database is not available: %s", err)
+	}
+
+	db := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(
+		pgdriver.WithDSN(dsn),
+		pgdriver.WithConnParams(map[string]interface{}{
+			"search_path":    schema,
+			"enable_seqscan": "off",
+		}),
+	)), pgdialect.New())
+
+	t.Cleanup(func() {
+		db.Close()
+
+		cleanup := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn))), pgdialect.New())
+		defer cleanup.Close()
+
+		_, err := cleanup.ExecContext(context.Background(), "DROP SCHEMA ? CASCADE", bun.Ident(schema))
+		if err != nil {
+			t.Errorf("drop schema %s: %s", schema, err)
+		}
+	})
+
+	return db
+}
+
+// testDatabase is a test schema with every migration applied.
+func testDatabase(t *testing.T) *bun.DB {
+	db := testSchema(t)
+	err := MigrateToDb(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func explain(t *testing.T, db *bun.DB, query *bun.SelectQuery) string {
+	var plan []string
+	err := db.NewRaw("EXPLAIN ?", query).Scan(context.Background(), &plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Join(plan, "\n")
+}
+
+// TestQueriesUseIndexes runs the key queries of the API through EXPLAIN and
+// checks that each one uses the index meant for it.
+func TestQueriesUseIndexes(t *testing.T) {
+	db := testDatabase(t)
+
+	wallet := "0xAdfD8DAa41c23c18064074416d3428a3086e1621"
+	txHash := "0x9d1b6e5e0a4b9fdbd1d3a9bdfe9b2b1a2f6c8d8e1c5b7a3f4e2d1c0b9a8f7e6d"
+	requestId := "42"
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	var prizeId BigInt
+	prizeId.SetInt64(4)
+
+	tests := []struct {
+		name  string
+		index string
+		query *bun.SelectQuery
+	}{
+		{
+			name:  "requests by wallet",
+			index: "request_random_wallet_address_idx",
+			query: WhereWallet(db.NewSelect().Model((*RequestRandom)(nil)), wallet),
+		},
+		{
+			name:  "requests by transaction",
+			index: "request_random_transaction_hash_idx",
+			query: db.NewSelect().Model((*RequestRandom)(nil)).Where("transaction_hash = ?", txHash),
+		},
+		{
+			name:  "request by id",
+			index: "request_random_request_id_idx",
+			query: db.NewSelect().Model((*RequestRandom)(nil)).Where("request_id = ?", requestId),
+		},
+		{
+			name:  "requests by time",
+			index: "request_random_time_idx",
+			query: db.NewSelect().Model((*RequestRandom)(nil)).Where("time >= ?", from).Where("time <= ?", to),
+		},
+		{
+			name:  "total spinning by wallet",
+			index: "request_random_wallet_address_idx",
+			query: WhereWallet(db.NewSelect().Model((*RequestRandom)(nil)).ColumnExpr("sum(amount)"), strings.ToLower(wallet)),
+		},
+		{
+			name:  "responses by wallet",
+			index: "response_random_wallet_address_idx",
+			query: WhereWallet(db.NewSelect().Model((*ResponseRandom)(nil)), wallet),
+		},
+		{
+			name:  "responses by transaction",
+			index: "response_random_transaction_hash_idx",
+			query: db.NewSelect().Model((*ResponseRandom)(nil)).Where("transaction_hash = ?", txHash),
+		},
+		{
+			name:  "response by id",
+			index: "response_random_request_id_idx",
+			query: db.NewSelect().Model((*ResponseRandom)(nil)).Where("request_id = ?", requestId),
+		},
+		{
+			name:  "responses by time",
+			index: "response_random_time_idx",
+			query: db.NewSelect().Model((*ResponseRandom)(nil)).Where("time >= ?", from).Where("time <= ?", to),
+		},
+		{
+			name:  "responses by prize",
+			index: "response_random_prize_ids_idx",
+			query: WherePrizeId(db.NewSelect().Model((*ResponseRandom)(nil)), prizeId),
+		},
+		{
+			name:  "vrf request by id",
+			index: "vrf_request_request_id_idx",
+			query: db.NewSelect().Model((*VrfRequest)(nil)).Where("request_id = ?", requestId),
+		},
+		{
+			name:  "vrf fulfillment by id",
+			index: "vrf_fulfillment_request_id_idx",
+			query: db.NewSelect().Model((*VrfFulfillment)(nil)).Where("request_id = ?", requestId),
+		},
+		{
+			name:  "failed randoms by wallet",
+			index: "failed_random_wallet_address_idx",
+			query: WhereWallet(db.NewSelect().Model((*FailedRandom)(nil)), wallet),
+		},
+		{
+			name:  "spins by wallet",
+			index: "spin_wallet_address_idx",
+			query: WhereWallet(db.NewSelect().Model((*Spin)(nil)), wallet),
+		},
+		{
+			name:  "spins by time",
+			index: "spin_time_idx",
+			query: db.NewSelect().Model((*Spin)(nil)).Where("time >= ?", from).Where("time <= ?", to),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan := explain(t, db, test.query)
+			if !strings.Contains(plan, test.index) {
+				t.Errorf("query does not use %s:\n%s", test.index, plan)
+			}
+		})
+	}
+}
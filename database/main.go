@@ -27,10 +27,5 @@ func ConnectDatabase() (*bun.DB, error) {
 	db := bun.NewDB(sqldb, pgdialect.New())
 	fmt.Println("connected to database")
 
-	err = CreateTable(db)
-	if err != nil {
-		return nil, err
-	}
-
 	return db, nil
 }
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/migrate"
+)
+
+// migrationFiles are the versioned changes of the schema, applied in the
+// order of their number. Each one is a pair of up and down SQL files, and the
+// applied ones are recorded in the bun_migrations table.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+func newMigrator(db *bun.DB) (*migrate.Migrator, error) {
+	migrations := migrate.NewMigrations()
+	err := migrations.Discover(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator := migrate.NewMigrator(db, migrations, migrate.WithMarkAppliedOnSuccess(true))
+	err = migrator.Init(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return migrator, nil
+}
+
+// MigrateToDb applies the migrations that have not been applied yet, as one
+// group that can be rolled back together.
+func MigrateToDb(db *bun.DB) error {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	group, err := migrator.Migrate(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if group.IsZero() {
+		fmt.Println("database: schema is up to date")
+		return nil
+	}
+
+	fmt.Println("database: migrated to", group)
+	return nil
+}
+
+// RollbackMigrationToDb rolls back the last group of applied migrations.
+func RollbackMigrationToDb(db *bun.DB) error {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	group, err := migrator.Rollback(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if group.IsZero() {
+		fmt.Println("database: no migration to roll back")
+		return nil
+	}
+
+	fmt.Println("database: rolled back", group)
+	return nil
+}
+
+// GetMigrationsFromDb returns every migration, applied or not, in order.
+func GetMigrationsFromDb(db *bun.DB) (migrate.MigrationSlice, error) {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrator.MigrationsWithStatus(context.Background())
+}
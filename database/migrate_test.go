@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+// baselineSchema is the schema the first release created from its models,
+// with rows of each kind, including a log stored twice.
+var baselineSchema = []string{
+	`CREATE TABLE "request_random" (
+		"id" BIGSERIAL NOT NULL,
+		"wallet_address" VARCHAR NOT NULL,
+		"request_id" VARCHAR NOT NULL,
+		"amount" BIGINT NOT NULL,
+		"transaction_hash" VARCHAR NOT NULL,
+		"index" BIGINT NOT NULL,
+		"time" TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY ("id")
+	)`,
+	`CREATE TABLE "response_random" (
+		"id" BIGSERIAL NOT NULL,
+		"wallet_address" VARCHAR NOT NULL,
+		"request_id" VARCHAR NOT NULL,
+		"prize_ids" JSONB NOT NULL,
+		"transaction_hash" VARCHAR NOT NULL,
+		"index" BIGINT NOT NULL,
+		"time" TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY ("id")
+	)`,
+	`CREATE TABLE "error_block" (
+		"id" BIGSERIAL NOT NULL,
+		"block" BIGINT NOT NULL,
+		PRIMARY KEY ("id")
+	)`,
+	`INSERT INTO "request_random" ("wallet_address", "request_id", "amount", "transaction_hash", "index", "time")
+		VALUES ('0xabc', '1', 3, '0x01', 0, now()), ('0xabc', '1', 3, '0x01', 0, now())`,
+	`INSERT INTO "response_random" ("wallet_address", "request_id", "prize_ids", "transaction_hash", "index", "time")
+		VALUES ('0xabc', '1', '[4, 7]', '0x02', 1, now())`,
+	`INSERT INTO "error_block" ("block") VALUES (25000000)`,
+}
+
+// TestMigrateBaselineSchema migrates a database created by the first release
+// and checks that its rows are kept under the current schema.
+func TestMigrateBaselineSchema(t *testing.T) {
+	db := testSchema(t)
+	ctx := context.Background()
+
+	for _, query := range baselineSchema {
+		_, err := db.ExecContext(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := MigrateToDb(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []RequestRandom
+	err = db.NewSelect().Model(&requests).Scan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want the duplicate removed", len(requests))
+	}
+	request := requests[0]
+	if request.ChainId != 56 || request.Contract != "0x0DF49Ee109bE77DA53d3050575e409D28D542ECC" {
+		t.Errorf("request got chain %d and contract %s", request.ChainId, request.Contract)
+	}
+	if request.Amount.Int64() != 3 || !request.Final {
+		t.Errorf("request got amount %s and final %t", request.Amount.String(), request.Final)
+	}
+
+	var response ResponseRandom
+	err = db.NewSelect().Model(&response).Scan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.PrizeIds) != 2 || response.PrizeIds[0].Int64() != 4 || response.PrizeIds[1].Int64() != 7 {
+		t.Errorf("response got prize ids %v", response.PrizeIds)
+	}
+
+	var blockErr BlockError
+	err = db.NewSelect().Model(&blockErr).Scan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blockErr.ChainId != 56 || blockErr.Block != 25000000 || blockErr.ToBlock != 25005000 {
+		t.Errorf("error block got chain %d and range %d-%d", blockErr.ChainId, blockErr.Block, blockErr.ToBlock)
+	}
+	if blockErr.RetryAt.IsZero() || !blockErr.ResolvedAt.IsZero() {
+		t.Errorf("error block got retry at %s and resolved at %s", blockErr.RetryAt, blockErr.ResolvedAt)
+	}
+
+	blockErrs, err := GetBlockErrorFromDb(db, 56, "0x0DF49Ee109bE77DA53d3050575e409D28D542ECC", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blockErrs) != 1 {
+		t.Errorf("got %d ranges to retry, want the old error block", len(blockErrs))
+	}
+}
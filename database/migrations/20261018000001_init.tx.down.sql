@@ -0,0 +1,45 @@
+DROP TABLE IF EXISTS "subscription_event";
+
+--bun:split
+
+DROP TABLE IF EXISTS "spin";
+
+--bun:split
+
+DROP TABLE IF EXISTS "prize_check";
+
+--bun:split
+
+DROP TABLE IF EXISTS "vrf_verification";
+
+--bun:split
+
+DROP TABLE IF EXISTS "failed_random";
+
+--bun:split
+
+DROP TABLE IF EXISTS "vrf_fulfillment";
+
+--bun:split
+
+DROP TABLE IF EXISTS "vrf_request";
+
+--bun:split
+
+DROP TABLE IF EXISTS "block";
+
+--bun:split
+
+DROP TABLE IF EXISTS "checkpoint";
+
+--bun:split
+
+DROP TABLE IF EXISTS "error_block";
+
+--bun:split
+
+DROP TABLE IF EXISTS "response_random";
+
+--bun:split
+
+DROP TABLE IF EXISTS "request_random";
@@ -0,0 +1,394 @@
+-- The schema the indexer created with CREATE TABLE IF NOT EXISTS before it
+-- had migrations, brought to its current form on a database created that way.
+--
+-- The first release only had request_random, response_random and a bare
+-- error_block, for the spinning contract on BSC. Their rows get chain 56 and
+-- that contract. The block of the old requests and responses is unknown, so
+-- they get block 0 until the range is indexed again, which updates them in
+-- place through their log key. The old error_block rows covered 5000 blocks
+-- and are due for a retry right away. The unique keys of the log tables drop
+-- the logs stored twice first.
+
+CREATE TABLE IF NOT EXISTS "request_random" (
+	"id"               BIGSERIAL NOT NULL,
+	"chain_id"         BIGINT NOT NULL,
+	"contract_address" VARCHAR NOT NULL,
+	"wallet_address"   VARCHAR NOT NULL,
+	"request_id"       VARCHAR NOT NULL,
+	"amount"           BIGINT NOT NULL,
+	"transaction_hash" VARCHAR NOT NULL,
+	"index"            BIGINT NOT NULL,
+	"block_number"     BIGINT NOT NULL,
+	"block_hash"       VARCHAR NOT NULL,
+	"final"            BOOLEAN NOT NULL,
+	"time"             TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY ("id")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "response_random" (
+	"id"               BIGSERIAL NOT NULL,
+	"chain_id"         BIGINT NOT NULL,
+	"contract_address" VARCHAR NOT NULL,
+	"wallet_address"   VARCHAR NOT NULL,
+	"request_id"       VARCHAR NOT NULL,
+	"prize_ids"        JSONB NOT NULL,
+	"transaction_hash" VARCHAR NOT NULL,
+	"index"            BIGINT NOT NULL,
+	"block_number"     BIGINT NOT NULL,
+	"block_hash"       VARCHAR NOT NULL,
+	"final"            BOOLEAN NOT NULL,
+	"time"             TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY ("id")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "error_block" (
+	"id"               BIGSERIAL NOT NULL,
+	"chain_id"         BIGINT NOT NULL,
+	"contract_address" VARCHAR NOT NULL,
+	"block"            BIGINT NOT NULL,
+	"to_block"         BIGINT NOT NULL,
+	"attempts"         BIGINT NOT NULL,
+	"last_error"       VARCHAR,
+	"retry_at"         TIMESTAMPTZ NOT NULL,
+	"resolved_at"      TIMESTAMPTZ,
+	PRIMARY KEY ("id")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "checkpoint" (
+	"chain_id"         BIGINT NOT NULL,
+	"contract_address" VARCHAR NOT NULL,
+	"block"            BIGINT NOT NULL,
+	"updated_at"       TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY ("chain_id", "contract_address")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "block" (
+	"chain_id"         BIGINT NOT NULL,
+	"contract_address" VARCHAR NOT NULL,
+	"number"           BIGINT NOT NULL,
+	"hash"             VARCHAR NOT NULL,
+	"parent_hash"      VARCHAR NOT NULL,
+	"time"             TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY ("chain_id", "contract_address", "number")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "vrf_request" (
+	"id"                            BIGSERIAL NOT NULL,
+	"chain_id"                      BIGINT NOT NULL,
+	"contract_address"              VARCHAR NOT NULL,
+	"coordinator_address"           VARCHAR NOT NULL,
+	"request_id"                    VARCHAR NOT NULL,
+	"key_hash"                      VARCHAR NOT NULL,
+	"pre_seed"                      NUMERIC NOT NULL,
+	"sub_id"                        BIGINT NOT NULL,
+	"minimum_request_confirmations" BIGINT NOT NULL,
+	"callback_gas_limit"            BIGINT NOT NULL,
+	"num_words"                     BIGINT NOT NULL,
+	"sender"                        VARCHAR NOT NULL,
+	"transaction_hash"              VARCHAR NOT NULL,
+	"index"                         BIGINT NOT NULL,
+	"block_number"                  BIGINT NOT NULL,
+	"block_hash"                    VARCHAR NOT NULL,
+	"final"                         BOOLEAN NOT NULL,
+	"time"                          TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY ("id")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "vrf_fulfillment" (
+	"id"                  BIGSERIAL NOT NULL,
+	"chain_id"            BIGINT NOT NULL,
+	"contract_address"    VARCHAR NOT NULL,
+	"coordinator_address" VARCHAR NOT NULL,
+	"request_id"          VARCHAR NOT NULL,
+	"output_seed"         NUMERIC NOT NULL,
+	"payment"             NUMERIC NOT NULL,
+	"success"             BOOLEAN NOT NULL,
+	"transaction_hash"    VARCHAR NOT NULL,
+	"index"               BIGINT NOT NULL,
+	"block_number"        BIGINT NOT NULL,
+	"block_hash"          VARCHAR NOT NULL,
+	"final"               BOOLEAN NOT NULL,
+	"time"                TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY ("id")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "failed_random" (
+	"id"               BIGSERIAL NOT NULL,
+	"chain_id"         BIGINT NOT NULL,
+	"contract_address" VARCHAR NOT NULL,
+	"wallet_address"   VARCHAR NOT NULL,
+	"request_id"       VARCHAR NOT NULL,
+	"gas_used"         BIGINT NOT NULL,
+	"reason"           VARCHAR NOT NULL,
+	"transaction_hash" VARCHAR NOT NULL,
+	"index"            BIGINT NOT NULL,
+	"block_number"     BIGINT NOT NULL,
+	"block_hash"       VARCHAR NOT NULL,
+	"final"            BOOLEAN NOT NULL,
+	"time"             TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY ("id")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "vrf_verification" (
+	"chain_id"         BIGINT NOT NULL,
+	"contract_address" VARCHAR NOT NULL,
+	"request_id"       VARCHAR NOT NULL,
+	"key_hash"         VARCHAR NOT NULL,
+	"transaction_hash" VARCHAR NOT NULL,
+	"block_number"     BIGINT NOT NULL,
+	"valid"            BOOLEAN NOT NULL,
+	"reason"           VARCHAR NOT NULL,
+	"verified_at"      TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY ("chain_id", "contract_address", "request_id")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "prize_check" (
+	"chain_id"          BIGINT NOT NULL,
+	"contract_address"  VARCHAR NOT NULL,
+	"request_id"        VARCHAR NOT NULL,
+	"model"             VARCHAR NOT NULL,
+	"emitted_prize_ids" JSONB NOT NULL,
+	"derived_prize_ids" JSONB,
+	"matched"           BOOLEAN NOT NULL,
+	"reason"            VARCHAR NOT NULL,
+	"block_number"      BIGINT NOT NULL,
+	"checked_at"        TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY ("chain_id", "contract_address", "request_id")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "spin" (
+	"chain_id"                 BIGINT NOT NULL,
+	"contract_address"         VARCHAR NOT NULL,
+	"request_id"               VARCHAR NOT NULL,
+	"wallet_address"           VARCHAR NOT NULL,
+	"amount"                   BIGINT NOT NULL,
+	"prize_ids"                JSONB NOT NULL,
+	"request_transaction_hash" VARCHAR NOT NULL,
+	"request_block_number"     BIGINT NOT NULL,
+	"request_time"             TIMESTAMPTZ NOT NULL,
+	"transaction_hash"         VARCHAR NOT NULL,
+	"block_number"             BIGINT NOT NULL,
+	"time"                     TIMESTAMPTZ NOT NULL,
+	"latency_blocks"           BIGINT NOT NULL,
+	"latency_seconds"          DOUBLE PRECISION NOT NULL,
+	"final"                    BOOLEAN NOT NULL,
+	PRIMARY KEY ("chain_id", "contract_address", "request_id")
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS "subscription_event" (
+	"id"               BIGSERIAL NOT NULL,
+	"chain_id"         BIGINT NOT NULL,
+	"contract_address" VARCHAR NOT NULL,
+	"sub_id"           BIGINT NOT NULL,
+	"kind"             VARCHAR NOT NULL,
+	"amount"           NUMERIC NOT NULL,
+	"balance"          NUMERIC NOT NULL,
+	"consumer"         VARCHAR NOT NULL,
+	"request_id"       VARCHAR NOT NULL,
+	"transaction_hash" VARCHAR NOT NULL,
+	"index"            BIGINT NOT NULL,
+	"block_number"     BIGINT NOT NULL,
+	"block_hash"       VARCHAR NOT NULL,
+	"final"            BOOLEAN NOT NULL,
+	"time"             TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY ("id")
+);
+
+--bun:split
+
+ALTER TABLE "request_random" ADD COLUMN IF NOT EXISTS "chain_id" BIGINT NOT NULL DEFAULT 56;
+
+--bun:split
+
+ALTER TABLE "request_random" ADD COLUMN IF NOT EXISTS "contract_address" VARCHAR NOT NULL DEFAULT '0x0DF49Ee109bE77DA53d3050575e409D28D542ECC';
+
+--bun:split
+
+ALTER TABLE "request_random" ADD COLUMN IF NOT EXISTS "block_number" BIGINT NOT NULL DEFAULT 0;
+
+--bun:split
+
+ALTER TABLE "request_random" ADD COLUMN IF NOT EXISTS "block_hash" VARCHAR NOT NULL DEFAULT '';
+
+--bun:split
+
+ALTER TABLE "request_random" ADD COLUMN IF NOT EXISTS "final" BOOLEAN NOT NULL DEFAULT TRUE;
+
+--bun:split
+
+ALTER TABLE "response_random" ADD COLUMN IF NOT EXISTS "chain_id" BIGINT NOT NULL DEFAULT 56;
+
+--bun:split
+
+ALTER TABLE "response_random" ADD COLUMN IF NOT EXISTS "contract_address" VARCHAR NOT NULL DEFAULT '0x0DF49Ee109bE77DA53d3050575e409D28D542ECC';
+
+--bun:split
+
+ALTER TABLE "response_random" ADD COLUMN IF NOT EXISTS "block_number" BIGINT NOT NULL DEFAULT 0;
+
+--bun:split
+
+ALTER TABLE "response_random" ADD COLUMN IF NOT EXISTS "block_hash" VARCHAR NOT NULL DEFAULT '';
+
+--bun:split
+
+ALTER TABLE "response_random" ADD COLUMN IF NOT EXISTS "final" BOOLEAN NOT NULL DEFAULT TRUE;
+
+--bun:split
+
+ALTER TABLE "request_random"
+	ALTER COLUMN "chain_id" DROP DEFAULT,
+	ALTER COLUMN "contract_address" DROP DEFAULT,
+	ALTER COLUMN "block_number" DROP DEFAULT,
+	ALTER COLUMN "block_hash" DROP DEFAULT,
+	ALTER COLUMN "final" DROP DEFAULT;
+
+--bun:split
+
+ALTER TABLE "response_random"
+	ALTER COLUMN "chain_id" DROP DEFAULT,
+	ALTER COLUMN "contract_address" DROP DEFAULT,
+	ALTER COLUMN "block_number" DROP DEFAULT,
+	ALTER COLUMN "block_hash" DROP DEFAULT,
+	ALTER COLUMN "final" DROP DEFAULT;
+
+--bun:split
+
+ALTER TABLE "error_block" ADD COLUMN IF NOT EXISTS "chain_id" BIGINT NOT NULL DEFAULT 56;
+
+--bun:split
+
+ALTER TABLE "error_block" ADD COLUMN IF NOT EXISTS "contract_address" VARCHAR NOT NULL DEFAULT '0x0DF49Ee109bE77DA53d3050575e409D28D542ECC';
+
+--bun:split
+
+ALTER TABLE "error_block" ADD COLUMN IF NOT EXISTS "to_block" BIGINT;
+
+--bun:split
+
+UPDATE "error_block" SET "to_block" = "block" + 5000 WHERE "to_block" IS NULL;
+
+--bun:split
+
+ALTER TABLE "error_block" ADD COLUMN IF NOT EXISTS "attempts" BIGINT NOT NULL DEFAULT 0;
+
+--bun:split
+
+ALTER TABLE "error_block" ADD COLUMN IF NOT EXISTS "last_error" VARCHAR;
+
+--bun:split
+
+ALTER TABLE "error_block" ADD COLUMN IF NOT EXISTS "retry_at" TIMESTAMPTZ NOT NULL DEFAULT now();
+
+--bun:split
+
+ALTER TABLE "error_block" ADD COLUMN IF NOT EXISTS "resolved_at" TIMESTAMPTZ;
+
+--bun:split
+
+ALTER TABLE "error_block"
+	ALTER COLUMN "chain_id" DROP DEFAULT,
+	ALTER COLUMN "contract_address" DROP DEFAULT,
+	ALTER COLUMN "to_block" SET NOT NULL,
+	ALTER COLUMN "attempts" DROP DEFAULT,
+	ALTER COLUMN "retry_at" DROP DEFAULT;
+
+--bun:split
+
+DELETE FROM "request_random" AS a
+USING "request_random" AS b
+WHERE a.chain_id = b.chain_id
+	AND a.transaction_hash = b.transaction_hash
+	AND a.index = b.index
+	AND a.id > b.id;
+
+--bun:split
+
+CREATE UNIQUE INDEX IF NOT EXISTS "request_random_log_key" ON "request_random" ("chain_id", "transaction_hash", "index");
+
+--bun:split
+
+DELETE FROM "response_random" AS a
+USING "response_random" AS b
+WHERE a.chain_id = b.chain_id
+	AND a.transaction_hash = b.transaction_hash
+	AND a.index = b.index
+	AND a.id > b.id;
+
+--bun:split
+
+CREATE UNIQUE INDEX IF NOT EXISTS "response_random_log_key" ON "response_random" ("chain_id", "transaction_hash", "index");
+
+--bun:split
+
+DELETE FROM "vrf_request" AS a
+USING "vrf_request" AS b
+WHERE a.chain_id = b.chain_id
+	AND a.transaction_hash = b.transaction_hash
+	AND a.index = b.index
+	AND a.id > b.id;
+
+--bun:split
+
+CREATE UNIQUE INDEX IF NOT EXISTS "vrf_request_log_key" ON "vrf_request" ("chain_id", "transaction_hash", "index");
+
+--bun:split
+
+DELETE FROM "vrf_fulfillment" AS a
+USING "vrf_fulfillment" AS b
+WHERE a.chain_id = b.chain_id
+	AND a.transaction_hash = b.transaction_hash
+	AND a.index = b.index
+	AND a.id > b.id;
+
+--bun:split
+
+CREATE UNIQUE INDEX IF NOT EXISTS "vrf_fulfillment_log_key" ON "vrf_fulfillment" ("chain_id", "transaction_hash", "index");
+
+--bun:split
+
+DELETE FROM "failed_random" AS a
+USING "failed_random" AS b
+WHERE a.chain_id = b.chain_id
+	AND a.transaction_hash = b.transaction_hash
+	AND a.index = b.index
+	AND a.id > b.id;
+
+--bun:split
+
+CREATE UNIQUE INDEX IF NOT EXISTS "failed_random_log_key" ON "failed_random" ("chain_id", "transaction_hash", "index");
+
+--bun:split
+
+DELETE FROM "subscription_event" AS a
+USING "subscription_event" AS b
+WHERE a.chain_id = b.chain_id
+	AND a.transaction_hash = b.transaction_hash
+	AND a.index = b.index
+	AND a.id > b.id;
+
+--bun:split
+
+CREATE UNIQUE INDEX IF NOT EXISTS "subscription_event_log_key" ON "subscription_event" ("chain_id", "transaction_hash", "index");
@@ -0,0 +1,23 @@
+-- Fails on the values that do not fit in a BIGINT anymore.
+
+ALTER TABLE "request_random" ALTER COLUMN "amount" TYPE BIGINT;
+
+--bun:split
+
+ALTER TABLE "response_random" ALTER COLUMN "prize_ids" TYPE JSONB USING to_jsonb("prize_ids");
+
+--bun:split
+
+ALTER TABLE "spin" ALTER COLUMN "amount" TYPE BIGINT;
+
+--bun:split
+
+ALTER TABLE "spin" ALTER COLUMN "prize_ids" TYPE JSONB USING to_jsonb("prize_ids");
+
+--bun:split
+
+ALTER TABLE "prize_check" ALTER COLUMN "emitted_prize_ids" TYPE JSONB USING to_jsonb("emitted_prize_ids");
+
+--bun:split
+
+ALTER TABLE "prize_check" ALTER COLUMN "derived_prize_ids" TYPE JSONB USING to_jsonb("derived_prize_ids");
@@ -0,0 +1,34 @@
+-- Amounts and prize ids are uint256 values of the contract, stored as NUMERIC
+-- so that values above 2^63 are kept as they are. The prize ids move from
+-- JSONB arrays to NUMERIC arrays.
+
+CREATE FUNCTION pg_temp.jsonb_to_numeric_array(value JSONB) RETURNS NUMERIC[] AS $$
+	SELECT CASE jsonb_typeof(value)
+		WHEN 'array' THEN ARRAY(SELECT element::NUMERIC FROM jsonb_array_elements_text(value) AS element)
+		ELSE '{}'::NUMERIC[]
+	END
+$$ LANGUAGE SQL IMMUTABLE STRICT;
+
+--bun:split
+
+ALTER TABLE "request_random" ALTER COLUMN "amount" TYPE NUMERIC;
+
+--bun:split
+
+ALTER TABLE "response_random" ALTER COLUMN "prize_ids" TYPE NUMERIC[] USING pg_temp.jsonb_to_numeric_array("prize_ids");
+
+--bun:split
+
+ALTER TABLE "spin" ALTER COLUMN "amount" TYPE NUMERIC;
+
+--bun:split
+
+ALTER TABLE "spin" ALTER COLUMN "prize_ids" TYPE NUMERIC[] USING pg_temp.jsonb_to_numeric_array("prize_ids");
+
+--bun:split
+
+ALTER TABLE "prize_check" ALTER COLUMN "emitted_prize_ids" TYPE NUMERIC[] USING pg_temp.jsonb_to_numeric_array("emitted_prize_ids");
+
+--bun:split
+
+ALTER TABLE "prize_check" ALTER COLUMN "derived_prize_ids" TYPE NUMERIC[] USING pg_temp.jsonb_to_numeric_array("derived_prize_ids");
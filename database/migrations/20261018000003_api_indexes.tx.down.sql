@@ -0,0 +1,57 @@
+DROP INDEX IF EXISTS "spin_time_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "spin_wallet_address_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "failed_random_request_id_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "failed_random_wallet_address_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "vrf_fulfillment_request_id_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "vrf_request_request_id_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "response_random_prize_ids_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "response_random_time_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "response_random_request_id_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "response_random_transaction_hash_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "response_random_wallet_address_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "request_random_time_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "request_random_request_id_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "request_random_transaction_hash_idx";
+
+--bun:split
+
+DROP INDEX IF EXISTS "request_random_wallet_address_idx";
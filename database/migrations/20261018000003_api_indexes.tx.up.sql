@@ -0,0 +1,60 @@
+-- Indexes for the filters of the API: wallet addresses are compared
+-- case-insensitively through lower(wallet_address), prize ids by containment.
+
+CREATE INDEX IF NOT EXISTS "request_random_wallet_address_idx" ON "request_random" (lower("wallet_address"));
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "request_random_transaction_hash_idx" ON "request_random" ("transaction_hash");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "request_random_request_id_idx" ON "request_random" ("request_id");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "request_random_time_idx" ON "request_random" ("time");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "response_random_wallet_address_idx" ON "response_random" (lower("wallet_address"));
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "response_random_transaction_hash_idx" ON "response_random" ("transaction_hash");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "response_random_request_id_idx" ON "response_random" ("request_id");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "response_random_time_idx" ON "response_random" ("time");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "response_random_prize_ids_idx" ON "response_random" USING GIN ("prize_ids");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "vrf_request_request_id_idx" ON "vrf_request" ("request_id");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "vrf_fulfillment_request_id_idx" ON "vrf_fulfillment" ("request_id");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "failed_random_wallet_address_idx" ON "failed_random" (lower("wallet_address"));
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "failed_random_request_id_idx" ON "failed_random" ("request_id");
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "spin_wallet_address_idx" ON "spin" (lower("wallet_address"));
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "spin_time_idx" ON "spin" ("time");
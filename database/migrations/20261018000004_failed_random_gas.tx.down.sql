@@ -0,0 +1 @@
+ALTER TABLE "failed_random" RENAME COLUMN "transaction_gas_used" TO "gas_used";
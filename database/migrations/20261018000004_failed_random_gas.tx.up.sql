@@ -0,0 +1,4 @@
+-- gas_used is the gas of the whole fulfillment transaction, not of the
+-- callback that failed.
+
+ALTER TABLE "failed_random" RENAME COLUMN "gas_used" TO "transaction_gas_used";
@@ -0,0 +1,20 @@
+DROP INDEX IF EXISTS "subscription_event_ledger_idx";
+
+--bun:split
+
+UPDATE "subscription_event" AS "sub"
+SET "funded_balance" = "ledger"."balance"
+FROM (
+	SELECT "id",
+		max("funded_balance") OVER (PARTITION BY "chain_id", "sub_id", "segment") -
+		coalesce(sum("amount") FILTER (WHERE "kind" = 'payment') OVER (PARTITION BY "chain_id", "sub_id", "segment" ORDER BY "block_number", "index"), 0) AS "balance"
+	FROM (
+		SELECT *, count(*) FILTER (WHERE "kind" IN ('funded', 'canceled')) OVER (PARTITION BY "chain_id", "sub_id" ORDER BY "block_number", "index") AS "segment"
+		FROM "subscription_event"
+	) AS "segments"
+) AS "ledger"
+WHERE "sub"."id" = "ledger"."id";
+
+--bun:split
+
+ALTER TABLE "subscription_event" RENAME COLUMN "funded_balance" TO "balance";
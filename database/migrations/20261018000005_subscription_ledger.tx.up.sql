@@ -0,0 +1,13 @@
+-- The balance of a subscription is computed when the ledger is read, in block
+-- order, so only the balance the coordinator reports when it is funded is
+-- stored. The ledger is read by chain and subscription.
+
+ALTER TABLE "subscription_event" RENAME COLUMN "balance" TO "funded_balance";
+
+--bun:split
+
+UPDATE "subscription_event" SET "funded_balance" = 0 WHERE "kind" <> 'funded';
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS "subscription_event_ledger_idx" ON "subscription_event" ("chain_id", "sub_id", "block_number", "index");
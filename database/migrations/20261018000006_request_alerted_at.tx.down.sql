@@ -0,0 +1 @@
+ALTER TABLE "request_random" DROP COLUMN IF EXISTS "alerted_at";
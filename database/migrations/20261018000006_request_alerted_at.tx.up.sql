@@ -0,0 +1,4 @@
+-- The watchdog stores when it alerted a pending request, so that it is not
+-- alerted again after a restart.
+
+ALTER TABLE "request_random" ADD COLUMN IF NOT EXISTS "alerted_at" TIMESTAMPTZ;
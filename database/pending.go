@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// PendingRequest is a request of the contract that has no response yet. The
+// age in blocks is counted up to the last block indexed for the contract,
+// Failed tells whether its fulfillment reached the coordinator but failed.
+type PendingRequest struct {
+	RequestRandom `bun:",extend"`
+	AgeBlocks     int64   `bun:"age_blocks" json:"ageBlocks"`
+	AgeSeconds    float64 `bun:"age_seconds" json:"ageSeconds"`
+	Failed        bool    `bun:"failed" json:"failed"`
+}
+
+// PendingRequestQuery selects the pending requests with their age. Filters
+// can be added on the columns of request_random.
+func PendingRequestQuery(db bun.IDB, data *[]PendingRequest) *bun.SelectQuery {
+	return db.NewSelect().
+		Model(data).
+		ColumnExpr("req.*").
+		ColumnExpr("(?) - req.block_number AS age_blocks", db.NewSelect().
+			Model((*Checkpoint)(nil)).
+			Column("block").
+			Where("checkpoint.chain_id = req.chain_id").
+			Where("checkpoint.contract_address = req.contract_address")).
+		ColumnExpr("EXTRACT(EPOCH FROM now() - req.time) AS age_seconds").
+		ColumnExpr("EXISTS (?) AS failed", db.NewSelect().
+			Model((*FailedRandom)(nil)).
+			ColumnExpr("1").
+			Where("fail.chain_id = req.chain_id").
+			Where("fail.contract_address = req.contract_address").
+			Where("fail.request_id = req.request_id")).
+		Where("NOT EXISTS (?)", db.NewSelect().
+			Model((*ResponseRandom)(nil)).
+			ColumnExpr("1").
+			Where("res.chain_id = req.chain_id").
+			Where("res.contract_address = req.contract_address").
+			Where("res.request_id = req.request_id"))
+}
+
+// GetPendingRequestsFromDb returns the pending requests of a contract that
+// are more than minAgeBlocks blocks old and have not been alerted yet, oldest
+// first.
+func GetPendingRequestsFromDb(db bun.IDB, chainId int64, contract string, minAgeBlocks int64, limit int) ([]PendingRequest, error) {
+	var data []PendingRequest
+	err := PendingRequestQuery(db, &data).
+		Where("req.chain_id = ?", chainId).
+		Where("req.contract_address = ?", contract).
+		Where("req.alerted_at IS NULL").
+		Where("req.block_number < (?) - ?", db.NewSelect().
+			Model((*Checkpoint)(nil)).
+			Column("block").
+			Where("checkpoint.chain_id = req.chain_id").
+			Where("checkpoint.contract_address = req.contract_address"), minAgeBlocks).
+		Order("req.block_number ASC").
+		Limit(limit).
+		Scan(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// UpdateRequestAlertedToDb records that the watchdog alerted the request.
+func UpdateRequestAlertedToDb(db bun.IDB, request *RequestRandom) error {
+	request.AlertedAt = time.Now()
+	_, err := db.NewUpdate().
+		Model(request).
+		Column("alerted_at").
+		WherePK().
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
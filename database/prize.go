@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// PrizeCheck compares the prize ids a contract emitted for a request with the
+// ones its wheel model derives from the random words of the fulfillment.
+// Reason is set when the model could not derive them.
+type PrizeCheck struct {
+	bun.BaseModel `bun:"table:prize_check,alias:pchk"`
+	ChainId       int64     `bun:"chain_id,pk" json:"chainId"`
+	Contract      string    `bun:"contract_address,pk" json:"contract"`
+	RequestId     string    `bun:"request_id,pk" json:"requestId"`
+	Model         string    `bun:"model,notnull" json:"model"`
+	Emitted       []BigInt  `bun:"emitted_prize_ids,array,type:numeric[],notnull" json:"emittedPrizeIds"`
+	Derived       []BigInt  `bun:"derived_prize_ids,array,type:numeric[]" json:"derivedPrizeIds"`
+	Match         bool      `bun:"matched,notnull" json:"match"`
+	Reason        string    `bun:"reason,notnull" json:"reason"`
+	BlockNumber   int64     `bun:"block_number,notnull" json:"blockNumber"`
+	CheckedAt     time.Time `bun:"checked_at,notnull" json:"checkedAt"`
+}
+
+// FulfilledSpin is a response of the contract together with the fulfillment
+// and the request of the random words it was made from.
+type FulfilledSpin struct {
+	RequestId   string   `bun:"request_id"`
+	PrizeIds    []BigInt `bun:"prize_ids,array"`
+	BlockNumber int64    `bun:"block_number"`
+	OutputSeed  string   `bun:"output_seed"`
+	NumWords    int64    `bun:"num_words"`
+}
+
+func UpsertPrizeCheckToDb(db bun.IDB, data []PrizeCheck) error {
+	if data == nil {
+		return nil
+	}
+
+	_, err := db.NewInsert().
+		Model(&data).
+		On("CONFLICT (chain_id, contract_address, request_id) DO UPDATE").
+		Set("model = EXCLUDED.model").
+		Set("emitted_prize_ids = EXCLUDED.emitted_prize_ids").
+		Set("derived_prize_ids = EXCLUDED.derived_prize_ids").
+		Set("matched = EXCLUDED.matched").
+		Set("reason = EXCLUDED.reason").
+		Set("block_number = EXCLUDED.block_number").
+		Set("checked_at = EXCLUDED.checked_at").
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("database: inserted to db")
+	return nil
+}
+
+// GetUncheckedSpinsFromDb returns the successfully fulfilled spins of a
+// contract that have no prize check yet.
+func GetUncheckedSpinsFromDb(db bun.IDB, chainId int64, contract string, limit int) ([]FulfilledSpin, error) {
+	var spins []FulfilledSpin
+	err := db.NewSelect().
+		Model((*ResponseRandom)(nil)).
+		ColumnExpr("res.request_id, res.prize_ids, res.block_number").
+		ColumnExpr("vful.output_seed, vreq.num_words").
+		Join("JOIN vrf_fulfillment AS vful").
+		JoinOn("vful.chain_id = res.chain_id").
+		JoinOn("vful.contract_address = res.contract_address").
+		JoinOn("vful.request_id = res.request_id").
+		Join("JOIN vrf_request AS vreq").
+		JoinOn("vreq.chain_id = res.chain_id").
+		JoinOn("vreq.contract_address = res.contract_address").
+		JoinOn("vreq.request_id = res.request_id").
+		Where("res.chain_id = ?", chainId).
+		Where("res.contract_address = ?", contract).
+		Where("vful.success").
+		Where("NOT EXISTS (?)", db.NewSelect().
+			Model((*PrizeCheck)(nil)).
+			ColumnExpr("1").
+			Where("pchk.chain_id = res.chain_id").
+			Where("pchk.contract_address = res.contract_address").
+			Where("pchk.request_id = res.request_id")).
+		Order("res.id").
+		Limit(limit).
+		Scan(context.Background(), &spins)
+	if err != nil {
+		return nil, err
+	}
+
+	return spins, nil
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// Spin pairs a request of the contract with its response. BlockNumber, Time
+// and Final are the ones of the response, so a spin is finalized and rolled
+// back together with it. The latency is the time the request took to be
+// fulfilled.
+type Spin struct {
+	bun.BaseModel  `bun:"table:spin,alias:spin"`
+	ChainId        int64     `bun:"chain_id,pk" json:"chainId"`
+	Contract       string    `bun:"contract_address,pk" json:"contract"`
+	RequestId      string    `bun:"request_id,pk" json:"requestId"`
+	User           string    `bun:"wallet_address,notnull" json:"user"`
+	Amount         BigInt    `bun:"amount,type:numeric,notnull" json:"amount"`
+	PrizeIds       []BigInt  `bun:"prize_ids,array,type:numeric[],notnull" json:"prizeIds"`
+	RequestTxHash  string    `bun:"request_transaction_hash,notnull" json:"requestTxHash"`
+	RequestBlock   int64     `bun:"request_block_number,notnull" json:"requestBlockNumber"`
+	RequestTime    time.Time `bun:"request_time,notnull" json:"requestTime"`
+	ResponseTxHash string    `bun:"transaction_hash,notnull" json:"txHash"`
+	BlockNumber    int64     `bun:"block_number,notnull" json:"blockNumber"`
+	Time           time.Time `bun:"time,notnull" json:"time"`
+	LatencyBlocks  int64     `bun:"latency_blocks,notnull" json:"latencyBlocks"`
+	LatencySeconds float64   `bun:"latency_seconds,notnull" json:"latencySeconds"`
+	Final          bool      `bun:"final,notnull" json:"final"`
+}
+
+// MatchSpinsToDb pairs the stored requests and responses of the given request
+// ids into spins. Spins that already exist are left as they are, so a range
+// can be matched again.
+func MatchSpinsToDb(db bun.IDB, chainId int64, contract string, requestIds []string) error {
+	if len(requestIds) == 0 {
+		return nil
+	}
+
+	_, err := db.ExecContext(context.Background(), `
+		INSERT INTO spin (
+			chain_id, contract_address, request_id, wallet_address, amount, prize_ids,
+			request_transaction_hash, request_block_number, request_time,
+			transaction_hash, block_number, time, latency_blocks, latency_seconds, final
+		)
+		SELECT
+			req.chain_id, req.contract_address, req.request_id, req.wallet_address, req.amount, res.prize_ids,
+			req.transaction_hash, req.block_number, req.time,
+			res.transaction_hash, res.block_number, res.time,
+			res.block_number - req.block_number, EXTRACT(EPOCH FROM res.time - req.time), req.final AND res.final
+		FROM request_random AS req
+		JOIN response_random AS res
+			ON res.chain_id = req.chain_id
+			AND res.contract_address = req.contract_address
+			AND res.request_id = req.request_id
+		WHERE req.chain_id = ?
+			AND req.contract_address = ?
+			AND req.request_id IN (?)
+		ON CONFLICT (chain_id, contract_address, request_id) DO NOTHING`,
+		chainId, contract, bun.In(requestIds))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// spinRequestIds returns the request ids of the requests and responses of a
+// range, the spins they may complete.
+func spinRequestIds(events *RangeEvents) []string {
+	var requestIds []string
+	for _, request := range events.Request {
+		requestIds = append(requestIds, request.RequestId)
+	}
+	for _, response := range events.Response {
+		requestIds = append(requestIds, response.RequestId)
+	}
+
+	return requestIds
+}
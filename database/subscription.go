@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+	"reflect"
+	"time"
+)
+
+// The kinds of entries of the subscription ledger.
+const (
+	SubscriptionFunded          = "funded"
+	SubscriptionPayment         = "payment"
+	SubscriptionConsumerAdded   = "consumer_added"
+	SubscriptionConsumerRemoved = "consumer_removed"
+	SubscriptionCanceled        = "canceled"
+)
+
+// SubscriptionEvent is an entry of the ledger of a VRF subscription, which is
+// keyed by chain and SubId: Contract is only the contract whose tracker
+// recorded the entry first. Amount is the LINK, in juels, the entry added to
+// or took from the subscription. FundedBalance is the balance the coordinator
+// reported in a funded entry, Balance the balance after the entry, computed
+// by SubscriptionLedgerQuery. Payments are only known for the requests of the
+// tracked contracts, so the balance drifts when the subscription is shared
+// with other consumers until it is funded again.
+type SubscriptionEvent struct {
+	bun.BaseModel `bun:"table:subscription_event,alias:sub"`
+	Id            int       `bun:"id,pk,autoincrement" json:"id"`
+	ChainId       int64     `bun:"chain_id,notnull,unique:subscription_event_log_key" json:"chainId"`
+	Contract      string    `bun:"contract_address,notnull" json:"contract"`
+	SubId         uint64    `bun:"sub_id,notnull" json:"subId"`
+	Kind          string    `bun:"kind,notnull" json:"kind"`
+	Amount        string    `bun:"amount,type:numeric,notnull" json:"amount"`
+	FundedBalance string    `bun:"funded_balance,type:numeric,notnull" json:"-"`
+	Balance       string    `bun:"balance,scanonly" json:"balance"`
+	Consumer      string    `bun:"consumer,notnull" json:"consumer"`
+	RequestId     string    `bun:"request_id,notnull" json:"requestId"`
+	TxHash        string    `bun:"transaction_hash,notnull,unique:subscription_event_log_key" json:"txHash"`
+	Index         int       `bun:"index,notnull,unique:subscription_event_log_key" json:"index"`
+	BlockNumber   int64     `bun:"block_number,notnull" json:"blockNumber"`
+	BlockHash     string    `bun:"block_hash,notnull" json:"blockHash"`
+	Final         bool      `bun:"final,notnull" json:"final"`
+	Time          time.Time `bun:"time,notnull" json:"time"`
+}
+
+// InsertSubscriptionEventToDb stores the ledger entries. An entry recorded by
+// the tracker of another contract of the subscription keeps its contract.
+func InsertSubscriptionEventToDb(db bun.IDB, data []SubscriptionEvent) error {
+	if data == nil {
+		return nil
+	}
+
+	_, err := onLogConflict(db, db.NewInsert().Model(&data), (*SubscriptionEvent)(nil), "contract_address").
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("database: inserted to db")
+	return nil
+}
+
+// SubscriptionLedgerQuery selects every ledger entry with its Balance. The
+// entries of a subscription are split at each funded or canceled entry, which
+// sets the balance, and the payments since then are taken from it, in
+// (block_number, index) order. Select from it as "sub" and filter there, so
+// that the balances are computed over the whole ledger.
+func SubscriptionLedgerQuery(db bun.IDB) *bun.SelectQuery {
+	segments := db.NewSelect().
+		Model((*SubscriptionEvent)(nil)).
+		ColumnExpr("sub.*").
+		ColumnExpr("count(*) FILTER (WHERE kind IN (?)) OVER (PARTITION BY chain_id, sub_id ORDER BY block_number, index) AS segment",
+			bun.In([]string{SubscriptionFunded, SubscriptionCanceled}))
+
+	ledger := db.NewSelect().TableExpr("(?) AS sub", segments)
+	table := db.Dialect().Tables().Get(reflect.TypeOf((*SubscriptionEvent)(nil)).Elem())
+	for _, field := range table.Fields {
+		ledger = ledger.ColumnExpr("sub.?", bun.Ident(field.Name))
+	}
+
+	return ledger.ColumnExpr("max(funded_balance) OVER (PARTITION BY chain_id, sub_id, segment) - "+
+		"coalesce(sum(amount) FILTER (WHERE kind = ?) OVER (PARTITION BY chain_id, sub_id, segment ORDER BY block_number, index), 0) AS balance",
+		SubscriptionPayment)
+}
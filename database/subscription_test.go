@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// TestSubscriptionLedgerBalance stores the ledger of a subscription out of
+// block order, by two contracts, and checks the balance of every entry.
+func TestSubscriptionLedgerBalance(t *testing.T) {
+	db := testDatabase(t)
+
+	entry := func(contract, kind, amount, fundedBalance string, block int64) SubscriptionEvent {
+		return SubscriptionEvent{
+			ChainId:       56,
+			Contract:      contract,
+			SubId:         7,
+			Kind:          kind,
+			Amount:        amount,
+			FundedBalance: fundedBalance,
+			TxHash:        fmt.Sprintf("0x%x", block),
+			Index:         int(block),
+			BlockNumber:   block,
+		}
+	}
+
+	ranges := [][]SubscriptionEvent{
+		{entry("0x02", SubscriptionPayment, "3", "0", 30)},
+		{entry("0x01", SubscriptionFunded, "100", "100", 10), entry("0x01", SubscriptionPayment, "5", "0", 20)},
+		{entry("0x01", SubscriptionFunded, "50", "145", 40), entry("0x01", SubscriptionPayment, "2", "0", 50)},
+	}
+	for _, data := range ranges {
+		err := InsertSubscriptionEventToDb(db, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the funded entry is recorded again by the other contract
+	err := InsertSubscriptionEventToDb(db, []SubscriptionEvent{entry("0x02", SubscriptionFunded, "100", "100", 10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ledger []SubscriptionEvent
+	err = db.NewSelect().
+		Model(&ledger).
+		ModelTableExpr("(?) AS sub", SubscriptionLedgerQuery(db)).
+		ColumnExpr("sub.*").
+		Order("block_number ASC").
+		Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"100", "95", "92", "145", "143"}
+	if len(ledger) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(ledger), len(want))
+	}
+	if ledger[0].Contract != "0x01" {
+		t.Errorf("funded entry moved to contract %s", ledger[0].Contract)
+	}
+	for i, event := range ledger {
+		if event.Balance != want[i] {
+			t.Errorf("entry at block %d has balance %s, want %s", event.BlockNumber, event.Balance, want[i])
+		}
+	}
+}
+
+// TestRollbackSharedSubscription rolls back one of the contracts sharing a
+// subscription, whose ledger entries were partly recorded by the other one.
+func TestRollbackSharedSubscription(t *testing.T) {
+	db := testDatabase(t)
+
+	entry := func(contract string, subId uint64, block int64) SubscriptionEvent {
+		return SubscriptionEvent{
+			ChainId:       56,
+			Contract:      contract,
+			SubId:         subId,
+			Kind:          SubscriptionPayment,
+			Amount:        "1",
+			FundedBalance: "0",
+			TxHash:        fmt.Sprintf("0x%x", block),
+			Index:         int(subId),
+			BlockNumber:   block,
+		}
+	}
+
+	err := InsertSubscriptionEventToDb(db, []SubscriptionEvent{
+		entry("0x01", 7, 10),
+		entry("0x02", 7, 30),
+		entry("0x01", 7, 40),
+		entry("0x01", 8, 40),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = RollbackToDb(db, 56, "0x02", 7, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kept []SubscriptionEvent
+	err = db.NewSelect().Model(&kept).Order("sub_id ASC", "block_number ASC").Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(kept) != 2 || kept[0].SubId != 7 || kept[0].BlockNumber != 10 || kept[1].SubId != 8 {
+		t.Errorf("kept %d entries: %+v", len(kept), kept)
+	}
+}
@@ -2,38 +2,94 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/uptrace/bun"
+	"reflect"
 	"time"
 )
 
 type RequestRandom struct {
 	bun.BaseModel `bun:"table:request_random,alias:req"`
 	Id            int       `bun:"id,pk,autoincrement" json:"id"`
+	ChainId       int64     `bun:"chain_id,notnull,unique:request_random_log_key" json:"chainId"`
+	Contract      string    `bun:"contract_address,notnull" json:"contract"`
 	User          string    `bun:"wallet_address,notnull" json:"user"`
 	RequestId     string    `bun:"request_id,notnull" json:"requestId"`
-	Amount        int       `bun:"amount,notnull" json:"amount"`
-	TxHash        string    `bun:"transaction_hash,notnull" json:"txHash"`
-	Index         int       `bun:"index,notnull" json:"index"`
+	Amount        BigInt    `bun:"amount,type:numeric,notnull" json:"amount"`
+	TxHash        string    `bun:"transaction_hash,notnull,unique:request_random_log_key" json:"txHash"`
+	Index         int       `bun:"index,notnull,unique:request_random_log_key" json:"index"`
+	BlockNumber   int64     `bun:"block_number,notnull" json:"blockNumber"`
+	BlockHash     string    `bun:"block_hash,notnull" json:"blockHash"`
+	Final         bool      `bun:"final,notnull" json:"final"`
 	Time          time.Time `bun:"time,notnull" json:"time"`
+	// AlertedAt is when the watchdog alerted the request as pending.
+	AlertedAt time.Time `bun:"alerted_at,nullzero" json:"alertedAt"`
 }
 
 type ResponseRandom struct {
 	bun.BaseModel `bun:"table:response_random,alias:res"`
 	Id            int       `bun:"id,pk,autoincrement" json:"id"`
+	ChainId       int64     `bun:"chain_id,notnull,unique:response_random_log_key" json:"chainId"`
+	Contract      string    `bun:"contract_address,notnull" json:"contract"`
 	User          string    `bun:"wallet_address,notnull" json:"user"`
 	RequestId     string    `bun:"request_id,notnull" json:"requestId"`
-	PrizeIds      []int     `bun:"prize_ids,notnull" json:"prizeIds"`
-	TxHash        string    `bun:"transaction_hash,notnull" json:"txHash"`
-	Index         int       `bun:"index,notnull" json:"index"`
+	PrizeIds      []BigInt  `bun:"prize_ids,array,type:numeric[],notnull" json:"prizeIds"`
+	TxHash        string    `bun:"transaction_hash,notnull,unique:response_random_log_key" json:"txHash"`
+	Index         int       `bun:"index,notnull,unique:response_random_log_key" json:"index"`
+	BlockNumber   int64     `bun:"block_number,notnull" json:"blockNumber"`
+	BlockHash     string    `bun:"block_hash,notnull" json:"blockHash"`
+	Final         bool      `bun:"final,notnull" json:"final"`
 	Time          time.Time `bun:"time,notnull" json:"time"`
 }
 
 type BlockError struct {
 	bun.BaseModel `bun:"table:error_block"`
-	Id            int `bun:"id,pk,autoincrement" json:"id"`
-	Block         int `bun:"block,notnull" json:"block""`
+	Id            int       `bun:"id,pk,autoincrement" json:"id"`
+	ChainId       int64     `bun:"chain_id,notnull" json:"chainId"`
+	Contract      string    `bun:"contract_address,notnull" json:"contract"`
+	Block         int       `bun:"block,notnull" json:"block"`
+	ToBlock       int       `bun:"to_block,notnull" json:"toBlock"`
+	Attempts      int       `bun:"attempts,notnull" json:"attempts"`
+	LastError     string    `bun:"last_error" json:"lastError"`
+	RetryAt       time.Time `bun:"retry_at,notnull" json:"retryAt"`
+	ResolvedAt    time.Time `bun:"resolved_at,nullzero" json:"resolvedAt"`
+}
+
+type Block struct {
+	bun.BaseModel `bun:"table:block"`
+	ChainId       int64     `bun:"chain_id,pk" json:"chainId"`
+	Contract      string    `bun:"contract_address,pk" json:"contract"`
+	Number        int64     `bun:"number,pk" json:"number"`
+	Hash          string    `bun:"hash,notnull" json:"hash"`
+	ParentHash    string    `bun:"parent_hash,notnull" json:"parentHash"`
+	Time          time.Time `bun:"time,notnull" json:"time"`
+}
+
+// RangeEvents holds everything indexed for a contract in a block range.
+type RangeEvents struct {
+	Request        []RequestRandom
+	Response       []ResponseRandom
+	VrfRequest     []VrfRequest
+	VrfFulfillment []VrfFulfillment
+	Failed         []FailedRandom
+	Verification   []VrfVerification
+	Subscription   []SubscriptionEvent
+	Blocks         []Block
+	// Unresolved holds the failed fulfillments of requests sent outside the
+	// range. They are not stored, they are resolved against the stored
+	// requests right before the range is.
+	Unresolved []VrfFulfillment
+}
+
+type Checkpoint struct {
+	bun.BaseModel `bun:"table:checkpoint"`
+	ChainId       int64     `bun:"chain_id,pk" json:"chainId"`
+	Contract      string    `bun:"contract_address,pk" json:"contract"`
+	Block         int64     `bun:"block,notnull" json:"block"`
+	UpdatedAt     time.Time `bun:"updated_at,notnull" json:"updatedAt"`
 }
 
 func (e *RequestRandom) String() (*string, error) {
@@ -56,26 +112,7 @@ func (e *ResponseRandom) String() (*string, error) {
 	return &dataStr, nil
 }
 
-func CreateTable(db *bun.DB) error {
-	err := createRequestRandomTable(db)
-	if err != nil {
-		return err
-	}
-
-	err = createResponseRandomTable(db)
-	if err != nil {
-		return err
-	}
-
-	err = createBlockErrorTable(db)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func InsertRequestRandomToDb(db *bun.DB, data []RequestRandom) error {
+func InsertRequestRandomToDb(db bun.IDB, data []RequestRandom) error {
 	if data == nil {
 		return nil
 	}
@@ -90,8 +127,7 @@ func InsertRequestRandomToDb(db *bun.DB, data []RequestRandom) error {
 	//		Index:     event.Index,
 	//	})
 	//}
-	_, err := db.NewInsert().
-		Model(&data).
+	_, err := onLogConflict(db, db.NewInsert().Model(&data), (*RequestRandom)(nil), "alerted_at").
 		Exec(context.Background())
 	if err != nil {
 		return err
@@ -101,7 +137,7 @@ func InsertRequestRandomToDb(db *bun.DB, data []RequestRandom) error {
 	return nil
 }
 
-func InsertResponseRandomToDb(db *bun.DB, data []ResponseRandom) error {
+func InsertResponseRandomToDb(db bun.IDB, data []ResponseRandom) error {
 	if data == nil {
 		return nil
 	}
@@ -117,8 +153,7 @@ func InsertResponseRandomToDb(db *bun.DB, data []ResponseRandom) error {
 	//	})
 	//}
 
-	_, err := db.NewInsert().
-		Model(&data).
+	_, err := onLogConflict(db, db.NewInsert().Model(&data), (*ResponseRandom)(nil)).
 		Exec(context.Background())
 	if err != nil {
 		return err
@@ -128,9 +163,14 @@ func InsertResponseRandomToDb(db *bun.DB, data []ResponseRandom) error {
 	return nil
 }
 
-func InsertBlockErrorToDb(db *bun.DB, block int) error {
+func InsertBlockErrorToDb(db bun.IDB, chainId int64, contract string, block, toBlock int, lastError string) error {
 	blockErr := BlockError{
-		Block: block,
+		ChainId:   chainId,
+		Contract:  contract,
+		Block:     block,
+		ToBlock:   toBlock,
+		LastError: lastError,
+		RetryAt:   time.Now(),
 	}
 
 	_, err := db.NewInsert().
@@ -144,10 +184,91 @@ func InsertBlockErrorToDb(db *bun.DB, block int) error {
 	return nil
 }
 
-func createRequestRandomTable(db *bun.DB) error {
-	_, err := db.NewCreateTable().
-		Model((*RequestRandom)(nil)).
-		IfNotExists().
+// InsertEventsToDb writes the events of a block range together with the
+// contract checkpoint in a single transaction, so the checkpoint never points
+// past data that was not stored.
+func InsertEventsToDb(db *bun.DB, chainId int64, contract string, block int64, events *RangeEvents) error {
+	return db.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		err := insertRangeEventsToDb(tx, chainId, contract, events)
+		if err != nil {
+			return err
+		}
+
+		return UpdateCheckpointToDb(tx, chainId, contract, block)
+	})
+}
+
+func insertRangeEventsToDb(db bun.IDB, chainId int64, contract string, events *RangeEvents) error {
+	err := InsertRequestRandomToDb(db, events.Request)
+	if err != nil {
+		return err
+	}
+
+	err = InsertResponseRandomToDb(db, events.Response)
+	if err != nil {
+		return err
+	}
+
+	err = InsertVrfRequestToDb(db, events.VrfRequest)
+	if err != nil {
+		return err
+	}
+
+	err = InsertVrfFulfillmentToDb(db, events.VrfFulfillment)
+	if err != nil {
+		return err
+	}
+
+	err = InsertFailedRandomToDb(db, events.Failed)
+	if err != nil {
+		return err
+	}
+
+	err = UpsertVrfVerificationToDb(db, events.Verification)
+	if err != nil {
+		return err
+	}
+
+	err = MatchSpinsToDb(db, chainId, contract, spinRequestIds(events))
+	if err != nil {
+		return err
+	}
+
+	err = InsertSubscriptionEventToDb(db, events.Subscription)
+	if err != nil {
+		return err
+	}
+
+	return InsertBlockToDb(db, events.Blocks)
+}
+
+// SkipRangeToDb records a block range that could not be indexed or stored in
+// error_block and moves the contract checkpoint past it in one transaction.
+// Nothing of the range is stored, it is left to RetryBlockError as a whole.
+func SkipRangeToDb(db *bun.DB, chainId int64, contract string, block, toBlock int64, lastError string) error {
+	return db.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		err := InsertBlockErrorToDb(tx, chainId, contract, int(block), int(toBlock), lastError)
+		if err != nil {
+			return err
+		}
+
+		return UpdateCheckpointToDb(tx, chainId, contract, toBlock)
+	})
+}
+
+func UpdateCheckpointToDb(db bun.IDB, chainId int64, contract string, block int64) error {
+	checkpoint := Checkpoint{
+		ChainId:   chainId,
+		Contract:  contract,
+		Block:     block,
+		UpdatedAt: time.Now(),
+	}
+
+	_, err := db.NewInsert().
+		Model(&checkpoint).
+		On("CONFLICT (chain_id, contract_address) DO UPDATE").
+		Set("block = EXCLUDED.block").
+		Set("updated_at = EXCLUDED.updated_at").
 		Exec(context.Background())
 	if err != nil {
 		return err
@@ -156,10 +277,31 @@ func createRequestRandomTable(db *bun.DB) error {
 	return nil
 }
 
-func createResponseRandomTable(db *bun.DB) error {
-	_, err := db.NewCreateTable().
-		Model((*ResponseRandom)(nil)).
-		IfNotExists().
+// GetBlockErrorFromDb returns up to limit unresolved block ranges of a
+// contract that are due for a retry.
+func GetBlockErrorFromDb(db bun.IDB, chainId int64, contract string, limit int) ([]BlockError, error) {
+	var blockErrors []BlockError
+	err := db.NewSelect().
+		Model(&blockErrors).
+		Where("chain_id = ?", chainId).
+		Where("contract_address = ?", contract).
+		Where("resolved_at IS NULL").
+		Where("retry_at <= ?", time.Now()).
+		Order("block ASC").
+		Limit(limit).
+		Scan(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return blockErrors, nil
+}
+
+func UpdateBlockErrorToDb(db bun.IDB, blockErr *BlockError) error {
+	_, err := db.NewUpdate().
+		Model(blockErr).
+		Column("attempts", "last_error", "retry_at", "resolved_at").
+		WherePK().
 		Exec(context.Background())
 	if err != nil {
 		return err
@@ -168,10 +310,111 @@ func createResponseRandomTable(db *bun.DB) error {
 	return nil
 }
 
-func createBlockErrorTable(db *bun.DB) error {
-	_, err := db.NewCreateTable().
-		Model((*BlockError)(nil)).
-		IfNotExists().
+// ResolveBlockErrorToDb stores the events of a re-indexed block range and
+// marks the range as resolved in the same transaction.
+func ResolveBlockErrorToDb(db *bun.DB, blockErr *BlockError, events *RangeEvents) error {
+	return db.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		err := insertRangeEventsToDb(tx, blockErr.ChainId, blockErr.Contract, events)
+		if err != nil {
+			return err
+		}
+
+		resolved := *blockErr
+		resolved.ResolvedAt = time.Now()
+		return UpdateBlockErrorToDb(tx, &resolved)
+	})
+}
+
+// FinalizeEventsToDb flags every event of a chain at or below block as final,
+// in every table at once.
+func FinalizeEventsToDb(db *bun.DB, chainId int64, block int64) error {
+	return db.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, model := range eventModels() {
+			_, err := tx.NewUpdate().
+				Model(model).
+				Set("final = ?", true).
+				Where("chain_id = ?", chainId).
+				Where("final = ?", false).
+				Where("block_number <= ?", block).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// eventModels are the models of every table holding indexed events, all with
+// chain_id, contract_address, block_number and final columns.
+func eventModels() []interface{} {
+	return []interface{}{
+		(*RequestRandom)(nil),
+		(*ResponseRandom)(nil),
+		(*VrfRequest)(nil),
+		(*VrfFulfillment)(nil),
+		(*FailedRandom)(nil),
+		(*Spin)(nil),
+		(*SubscriptionEvent)(nil),
+	}
+}
+
+// logKeyColumns are the unique key of the tables holding one row per log, so
+// a range can be indexed again without duplicating its logs.
+var logKeyColumns = []string{"chain_id", "transaction_hash", "index"}
+
+// onLogConflict makes an insert of logs update the rows already stored for
+// the same logs instead of failing on the unique key. The keep columns are
+// left as they were stored.
+func onLogConflict(db bun.IDB, query *bun.InsertQuery, model interface{}, keep ...string) *bun.InsertQuery {
+	query = query.On("CONFLICT (?) DO UPDATE", bun.In(logKeyIdents()))
+
+	table := db.Dialect().Tables().Get(reflect.TypeOf(model).Elem())
+	for _, field := range table.DataFields {
+		if isLogKeyColumn(field.Name) || containsColumn(keep, field.Name) {
+			continue
+		}
+		query = query.Set("? = EXCLUDED.?", bun.Ident(field.Name), bun.Ident(field.Name))
+	}
+
+	return query
+}
+
+func logKeyIdents() []bun.Ident {
+	var idents []bun.Ident
+	for _, column := range logKeyColumns {
+		idents = append(idents, bun.Ident(column))
+	}
+
+	return idents
+}
+
+func isLogKeyColumn(name string) bool {
+	return containsColumn(logKeyColumns, name)
+}
+
+func containsColumn(columns []string, name string) bool {
+	for _, column := range columns {
+		if column == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func InsertBlockToDb(db bun.IDB, data []Block) error {
+	if data == nil {
+		return nil
+	}
+
+	_, err := db.NewInsert().
+		Model(&data).
+		On("CONFLICT (chain_id, contract_address, number) DO UPDATE").
+		Set("hash = EXCLUDED.hash").
+		Set("parent_hash = EXCLUDED.parent_hash").
+		Set("time = EXCLUDED.time").
 		Exec(context.Background())
 	if err != nil {
 		return err
@@ -179,3 +422,115 @@ func createBlockErrorTable(db *bun.DB) error {
 
 	return nil
 }
+
+func GetBlocksByNumberFromDb(db bun.IDB, chainId int64, numbers []int64) ([]Block, error) {
+	var blocks []Block
+	err := db.NewSelect().
+		Model(&blocks).
+		Where("chain_id = ?", chainId).
+		Where("number IN (?)", bun.In(numbers)).
+		Scan(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// GetBlocksFromDb returns up to limit blocks stored for a contract at or below
+// number, the highest first.
+func GetBlocksFromDb(db bun.IDB, chainId int64, contract string, number int64, limit int) ([]Block, error) {
+	var blocks []Block
+	err := db.NewSelect().
+		Model(&blocks).
+		Where("chain_id = ?", chainId).
+		Where("contract_address = ?", contract).
+		Where("number <= ?", number).
+		Order("number DESC").
+		Limit(limit).
+		Scan(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// RollbackToDb removes everything indexed for a contract above block after a
+// chain reorganization and moves the contract checkpoint back to it. The
+// events of subscription subId are removed whichever contract recorded them,
+// as a subscription shared by several contracts keeps a single row per log.
+func RollbackToDb(db *bun.DB, chainId int64, contract string, subId uint64, block int64) error {
+	return db.RunInTx(context.Background(), nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, model := range eventModels() {
+			if _, ok := model.(*SubscriptionEvent); ok {
+				continue
+			}
+
+			_, err := tx.NewDelete().
+				Model(model).
+				Where("chain_id = ?", chainId).
+				Where("contract_address = ?", contract).
+				Where("block_number > ?", block).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, model := range []interface{}{(*VrfVerification)(nil), (*PrizeCheck)(nil)} {
+			_, err := tx.NewDelete().
+				Model(model).
+				Where("chain_id = ?", chainId).
+				Where("contract_address = ?", contract).
+				Where("block_number > ?", block).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if subId != 0 {
+			_, err := tx.NewDelete().
+				Model((*SubscriptionEvent)(nil)).
+				Where("chain_id = ?", chainId).
+				Where("sub_id = ?", subId).
+				Where("block_number > ?", block).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.NewDelete().
+			Model((*Block)(nil)).
+			Where("chain_id = ?", chainId).
+			Where("contract_address = ?", contract).
+			Where("number > ?", block).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		return UpdateCheckpointToDb(tx, chainId, contract, block)
+	})
+}
+
+// GetCheckpointFromDb returns the last committed block of a contract, or nil
+// if the contract has never been indexed.
+func GetCheckpointFromDb(db bun.IDB, chainId int64, contract string) (*Checkpoint, error) {
+	checkpoint := new(Checkpoint)
+	err := db.NewSelect().
+		Model(checkpoint).
+		Where("chain_id = ?", chainId).
+		Where("contract_address = ?", contract).
+		Scan(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
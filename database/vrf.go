@@ -0,0 +1,226 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// VrfRequest is a RandomWordsRequested event of the VRF coordinator sent by
+// one of the tracked contracts. RequestId is the same id as the one of the
+// contract RequestCreated event.
+type VrfRequest struct {
+	bun.BaseModel               `bun:"table:vrf_request,alias:vreq"`
+	Id                          int       `bun:"id,pk,autoincrement" json:"id"`
+	ChainId                     int64     `bun:"chain_id,notnull,unique:vrf_request_log_key" json:"chainId"`
+	Contract                    string    `bun:"contract_address,notnull" json:"contract"`
+	Coordinator                 string    `bun:"coordinator_address,notnull" json:"coordinator"`
+	RequestId                   string    `bun:"request_id,notnull" json:"requestId"`
+	KeyHash                     string    `bun:"key_hash,notnull" json:"keyHash"`
+	PreSeed                     string    `bun:"pre_seed,type:numeric,notnull" json:"preSeed"`
+	SubId                       uint64    `bun:"sub_id,notnull" json:"subId"`
+	MinimumRequestConfirmations int       `bun:"minimum_request_confirmations,notnull" json:"minimumRequestConfirmations"`
+	CallbackGasLimit            int64     `bun:"callback_gas_limit,notnull" json:"callbackGasLimit"`
+	NumWords                    int64     `bun:"num_words,notnull" json:"numWords"`
+	Sender                      string    `bun:"sender,notnull" json:"sender"`
+	TxHash                      string    `bun:"transaction_hash,notnull,unique:vrf_request_log_key" json:"txHash"`
+	Index                       int       `bun:"index,notnull,unique:vrf_request_log_key" json:"index"`
+	BlockNumber                 int64     `bun:"block_number,notnull" json:"blockNumber"`
+	BlockHash                   string    `bun:"block_hash,notnull" json:"blockHash"`
+	Final                       bool      `bun:"final,notnull" json:"final"`
+	Time                        time.Time `bun:"time,notnull" json:"time"`
+}
+
+// VrfFulfillment is a RandomWordsFulfilled event of the VRF coordinator for a
+// request of one of the tracked contracts. Payment is in juels of LINK.
+type VrfFulfillment struct {
+	bun.BaseModel `bun:"table:vrf_fulfillment,alias:vful"`
+	Id            int       `bun:"id,pk,autoincrement" json:"id"`
+	ChainId       int64     `bun:"chain_id,notnull,unique:vrf_fulfillment_log_key" json:"chainId"`
+	Contract      string    `bun:"contract_address,notnull" json:"contract"`
+	Coordinator   string    `bun:"coordinator_address,notnull" json:"coordinator"`
+	RequestId     string    `bun:"request_id,notnull" json:"requestId"`
+	OutputSeed    string    `bun:"output_seed,type:numeric,notnull" json:"outputSeed"`
+	Payment       string    `bun:"payment,type:numeric,notnull" json:"payment"`
+	Success       bool      `bun:"success,notnull" json:"success"`
+	TxHash        string    `bun:"transaction_hash,notnull,unique:vrf_fulfillment_log_key" json:"txHash"`
+	Index         int       `bun:"index,notnull,unique:vrf_fulfillment_log_key" json:"index"`
+	BlockNumber   int64     `bun:"block_number,notnull" json:"blockNumber"`
+	BlockHash     string    `bun:"block_hash,notnull" json:"blockHash"`
+	Final         bool      `bun:"final,notnull" json:"final"`
+	Time          time.Time `bun:"time,notnull" json:"time"`
+}
+
+// FailedRandom is a spin whose fulfillment reached the coordinator but whose
+// callback failed, so the contract never emitted a ResponseCreated event.
+// TxGasUsed is the gas of the whole fulfillment transaction, not of the
+// callback alone, and Reason the revert reason of the callback when it could
+// be replayed.
+type FailedRandom struct {
+	bun.BaseModel `bun:"table:failed_random,alias:fail"`
+	Id            int       `bun:"id,pk,autoincrement" json:"id"`
+	ChainId       int64     `bun:"chain_id,notnull,unique:failed_random_log_key" json:"chainId"`
+	Contract      string    `bun:"contract_address,notnull" json:"contract"`
+	User          string    `bun:"wallet_address,notnull" json:"user"`
+	RequestId     string    `bun:"request_id,notnull" json:"requestId"`
+	TxGasUsed     int64     `bun:"transaction_gas_used,notnull" json:"txGasUsed"`
+	Reason        string    `bun:"reason,notnull" json:"reason"`
+	TxHash        string    `bun:"transaction_hash,notnull,unique:failed_random_log_key" json:"txHash"`
+	Index         int       `bun:"index,notnull,unique:failed_random_log_key" json:"index"`
+	BlockNumber   int64     `bun:"block_number,notnull" json:"blockNumber"`
+	BlockHash     string    `bun:"block_hash,notnull" json:"blockHash"`
+	Final         bool      `bun:"final,notnull" json:"final"`
+	Time          time.Time `bun:"time,notnull" json:"time"`
+}
+
+// VrfVerification is the verdict of the independent check of the VRF proof of
+// a fulfillment. Reason tells why the proof was rejected.
+type VrfVerification struct {
+	bun.BaseModel `bun:"table:vrf_verification,alias:vver"`
+	ChainId       int64     `bun:"chain_id,pk" json:"chainId"`
+	Contract      string    `bun:"contract_address,pk" json:"contract"`
+	RequestId     string    `bun:"request_id,pk" json:"requestId"`
+	KeyHash       string    `bun:"key_hash,notnull" json:"keyHash"`
+	TxHash        string    `bun:"transaction_hash,notnull" json:"txHash"`
+	BlockNumber   int64     `bun:"block_number,notnull" json:"blockNumber"`
+	Valid         bool      `bun:"valid,notnull" json:"valid"`
+	Reason        string    `bun:"reason,notnull" json:"reason"`
+	VerifiedAt    time.Time `bun:"verified_at,notnull" json:"verifiedAt"`
+}
+
+func InsertVrfRequestToDb(db bun.IDB, data []VrfRequest) error {
+	if data == nil {
+		return nil
+	}
+
+	_, err := onLogConflict(db, db.NewInsert().Model(&data), (*VrfRequest)(nil)).
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("database: inserted to db")
+	return nil
+}
+
+func InsertVrfFulfillmentToDb(db bun.IDB, data []VrfFulfillment) error {
+	if data == nil {
+		return nil
+	}
+
+	_, err := onLogConflict(db, db.NewInsert().Model(&data), (*VrfFulfillment)(nil)).
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("database: inserted to db")
+	return nil
+}
+
+// InsertFailedRandomToDb stores the failed spins, taking the wallet of each
+// one from its stored request. The wallet is left empty when the request has
+// not been indexed.
+func InsertFailedRandomToDb(db bun.IDB, data []FailedRandom) error {
+	if data == nil {
+		return nil
+	}
+
+	for i := range data {
+		if data[i].User != "" {
+			continue
+		}
+
+		err := db.NewSelect().
+			Model((*RequestRandom)(nil)).
+			Column("wallet_address").
+			Where("chain_id = ?", data[i].ChainId).
+			Where("contract_address = ?", data[i].Contract).
+			Where("request_id = ?", data[i].RequestId).
+			Limit(1).
+			Scan(context.Background(), &data[i].User)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	_, err := onLogConflict(db, db.NewInsert().Model(&data), (*FailedRandom)(nil)).
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("database: inserted to db")
+	return nil
+}
+
+// GetVrfRequestIdsFromDb returns the ids among requestIds of the requests
+// stored for a contract.
+func GetVrfRequestIdsFromDb(db bun.IDB, chainId int64, contract string, requestIds []string) ([]string, error) {
+	var stored []string
+	err := db.NewSelect().
+		Model((*VrfRequest)(nil)).
+		Column("request_id").
+		Where("chain_id = ?", chainId).
+		Where("contract_address = ?", contract).
+		Where("request_id IN (?)", bun.In(requestIds)).
+		Scan(context.Background(), &stored)
+	if err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// UpsertVrfVerificationToDb stores the verdicts, replacing the previous one
+// of a request when it is verified again.
+func UpsertVrfVerificationToDb(db bun.IDB, data []VrfVerification) error {
+	if data == nil {
+		return nil
+	}
+
+	_, err := db.NewInsert().
+		Model(&data).
+		On("CONFLICT (chain_id, contract_address, request_id) DO UPDATE").
+		Set("key_hash = EXCLUDED.key_hash").
+		Set("transaction_hash = EXCLUDED.transaction_hash").
+		Set("block_number = EXCLUDED.block_number").
+		Set("valid = EXCLUDED.valid").
+		Set("reason = EXCLUDED.reason").
+		Set("verified_at = EXCLUDED.verified_at").
+		Exec(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("database: inserted to db")
+	return nil
+}
+
+// GetUnverifiedFulfillmentsFromDb returns the fulfillments of a contract
+// after the fulfillment with id afterId that have no verdict yet, by id.
+func GetUnverifiedFulfillmentsFromDb(db bun.IDB, chainId int64, contract string, afterId int, limit int) ([]VrfFulfillment, error) {
+	var data []VrfFulfillment
+	err := db.NewSelect().
+		Model(&data).
+		Where("vful.chain_id = ?", chainId).
+		Where("vful.contract_address = ?", contract).
+		Where("vful.id > ?", afterId).
+		Where("NOT EXISTS (?)", db.NewSelect().
+			Model((*VrfVerification)(nil)).
+			ColumnExpr("1").
+			Where("vver.chain_id = vful.chain_id").
+			Where("vver.contract_address = vful.contract_address").
+			Where("vver.request_id = vful.request_id")).
+		Order("vful.id").
+		Limit(limit).
+		Scan(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
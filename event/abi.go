@@ -0,0 +1,115 @@
+package event
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"os"
+)
+
+//go:embed abi/spinning.json
+var spinningAbi []byte
+
+var (
+	ErrUnknownEvent   = errors.New("event: unknown event")
+	ErrEventSignature = errors.New("event: log does not match the event signature")
+)
+
+// DecodeError is returned when a log of a known event cannot be decoded.
+type DecodeError struct {
+	Event  string
+	TxHash common.Hash
+	Index  uint
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("event: decode %s in %s at index %d: %s", e.Event, e.TxHash, e.Index, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+type RequestCreatedEvent struct {
+	User      common.Address
+	RequestId *big.Int
+	Amount    *big.Int
+}
+
+type ResponseCreatedEvent struct {
+	User      common.Address
+	RequestId *big.Int
+	PrizeIds  []*big.Int
+}
+
+// LoadAbi reads the contract ABI from a JSON file, or returns the embedded ABI
+// of the spinning wheel contract when path is empty.
+func LoadAbi(path string) (*abi.ABI, error) {
+	data := spinningAbi
+	if path != "" {
+		file, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		data = file
+	}
+
+	contractAbi, err := abi.JSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{"RequestCreated", "ResponseCreated"} {
+		if _, ok := contractAbi.Events[name]; !ok {
+			return nil, fmt.Errorf("event: abi has no %s event", name)
+		}
+	}
+
+	return &contractAbi, nil
+}
+
+// UnpackLog decodes the topics and data of a log of the named event into out.
+func UnpackLog(contractAbi *abi.ABI, out interface{}, name string, vLog types.Log) error {
+	event, ok := contractAbi.Events[name]
+	if !ok {
+		return ErrUnknownEvent
+	}
+
+	decodeErr := &DecodeError{
+		Event:  name,
+		TxHash: vLog.TxHash,
+		Index:  vLog.Index,
+	}
+
+	var indexed abi.Arguments
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+
+	if len(vLog.Topics) == 0 || vLog.Topics[0] != event.ID || len(vLog.Topics)-1 != len(indexed) {
+		decodeErr.Err = ErrEventSignature
+		return decodeErr
+	}
+
+	err := contractAbi.UnpackIntoInterface(out, name, vLog.Data)
+	if err != nil {
+		decodeErr.Err = fmt.Errorf("%w: %s", ErrEventSignature, err)
+		return decodeErr
+	}
+
+	err = abi.ParseTopics(out, indexed, vLog.Topics[1:])
+	if err != nil {
+		decodeErr.Err = fmt.Errorf("%w: %s", ErrEventSignature, err)
+		return decodeErr
+	}
+
+	return nil
+}
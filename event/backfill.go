@@ -0,0 +1,139 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+	"golang.org/x/sync/errgroup"
+	"math/big"
+)
+
+type backfillResult struct {
+	fromBlock int64
+	toBlock   int64
+	events    *database.RangeEvents
+	err       error
+}
+
+// Backfill indexes the blocks from the checkpoint, or from number, up to the
+// last final block. Up to workers ranges are fetched concurrently, but they
+// are committed one by one in block order, so the checkpoint never moves past
+// a range that has not been stored or recorded in error_block yet. The stored
+// blocks before the checkpoint are checked for a reorg and rolled back first,
+// the way the regular tracker does. The regular tracker can take over from the
+// checkpoint once Backfill returns.
+func (tracking *TrackingEvent) Backfill(db *bun.DB, number *big.Int, workers int) error {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	fromBlock, err := tracking.startBlock(db, number)
+	if err != nil {
+		return err
+	}
+
+	ancestor, reorg, err := tracking.CheckReorg(db, fromBlock-1)
+	if err != nil {
+		return err
+	}
+
+	if reorg {
+		fmt.Println("reorg detected, rollback to block:", ancestor)
+		err = database.RollbackToDb(db, tracking.ChainId, tracking.Address.String(), tracking.SubscriptionId, ancestor)
+		if err != nil {
+			return err
+		}
+		fromBlock = ancestor + 1
+	}
+
+	lastestBlockNumber, err := tracking.GetLatestBlockNumber()
+	if err != nil {
+		return err
+	}
+
+	toBlock := lastestBlockNumber.Int64() - tracking.Confirmations
+	if fromBlock > toBlock {
+		return nil
+	}
+
+	size := tracking.Pool.maxBlockRange()
+	g, ctx := errgroup.WithContext(context.Background())
+	// every range waiting to be committed holds a slot, which bounds the
+	// number of ranges in flight to workers
+	pending := make(chan chan backfillResult, workers-1)
+
+	g.Go(func() error {
+		defer close(pending)
+		for start := fromBlock; start <= toBlock; start = start + size {
+			end := start + size - 1
+			if end > toBlock {
+				end = toBlock
+			}
+
+			result := make(chan backfillResult, 1)
+			select {
+			case pending <- result:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			start := start
+			g.Go(func() error {
+				events, err := tracking.getEventByRange(start, end)
+				result <- backfillResult{
+					fromBlock: start,
+					toBlock:   end,
+					events:    events,
+					err:       err,
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		for result := range pending {
+			var r backfillResult
+			select {
+			case r = <-result:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			err := tracking.commitBackfill(db, r)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return g.Wait()
+}
+
+// commitBackfill stores a fetched range. The failed fulfillments of the range
+// are resolved here rather than when fetching, as the requests of the ranges
+// before it are only stored by then.
+func (tracking *TrackingEvent) commitBackfill(db *bun.DB, r backfillResult) error {
+	if r.err == nil {
+		r.err = tracking.resolveFailed(db, r.events)
+	}
+	if r.err != nil {
+		fmt.Println(r.fromBlock, r.err)
+		_, err := tracking.skipRange(db, r.fromBlock, r.toBlock, r.err)
+		return err
+	}
+
+	setFinal(r.events, r.toBlock)
+	err := database.InsertEventsToDb(db, tracking.ChainId, tracking.Address.String(), r.toBlock, r.events)
+	if err != nil {
+		fmt.Println("insert events to db:", err)
+		_, err = tracking.skipRange(db, r.fromBlock, r.toBlock, err)
+		return err
+	}
+
+	fmt.Println(r.toBlock)
+	return nil
+}
@@ -0,0 +1,192 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"container/list"
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/uptrace/bun"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheSize = 10000
+	// headerBatchSize is the number of eth_getBlockByNumber calls sent in a
+	// single JSON-RPC batch.
+	headerBatchSize = 100
+)
+
+// BlockCache is a LRU cache of block headers by number. When DB is set the
+// headers are also looked up in the block table, so they survive restarts.
+// The cache never writes that table: its rows are only stored with the events
+// of their range, because the reorg check trusts them.
+type BlockCache struct {
+	DB *bun.DB
+
+	mu    sync.Mutex
+	size  int
+	items map[int64]*list.Element
+	order *list.List
+}
+
+func NewBlockCache(size int, db *bun.DB) *BlockCache {
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+
+	return &BlockCache{
+		DB:    db,
+		size:  size,
+		items: make(map[int64]*list.Element),
+		order: list.New(),
+	}
+}
+
+func (c *BlockCache) Get(number int64) (database.Block, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[number]
+	if !ok {
+		return database.Block{}, false
+	}
+
+	c.order.MoveToFront(item)
+	return item.Value.(database.Block), true
+}
+
+func (c *BlockCache) Add(block database.Block) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if item, ok := c.items[block.Number]; ok {
+		item.Value = block
+		c.order.MoveToFront(item)
+		return
+	}
+
+	c.items[block.Number] = c.order.PushFront(block)
+	if c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.items, last.Value.(database.Block).Number)
+	}
+}
+
+func (c *BlockCache) Remove(number int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[number]
+	if !ok {
+		return
+	}
+
+	c.order.Remove(item)
+	delete(c.items, number)
+}
+
+// GetBlocks returns the headers of the given blocks. Each header is taken from
+// the cache, then from the block table, and only then fetched from the RPC,
+// all missing headers in JSON-RPC batches. Fetched headers are only kept in
+// memory.
+func (tracking *TrackingEvent) GetBlocks(numbers []int64) (map[int64]database.Block, error) {
+	blocks := make(map[int64]database.Block)
+	var missing []int64
+	for _, number := range numbers {
+		if _, ok := blocks[number]; ok {
+			continue
+		}
+
+		block, ok := tracking.Cache.Get(number)
+		if ok {
+			blocks[number] = block
+			continue
+		}
+		missing = append(missing, number)
+	}
+
+	if len(missing) > 0 && tracking.Cache.DB != nil {
+		stored, err := database.GetBlocksByNumberFromDb(tracking.Cache.DB, tracking.ChainId, missing)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, block := range stored {
+			blocks[block.Number] = block
+			tracking.Cache.Add(block)
+		}
+
+		var stillMissing []int64
+		for _, number := range missing {
+			if _, ok := blocks[number]; !ok {
+				stillMissing = append(stillMissing, number)
+			}
+		}
+		missing = stillMissing
+	}
+
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	for start := 0; start < len(missing); start = start + headerBatchSize {
+		end := start + headerBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+
+		fetched, err := tracking.fetchBlocks(missing[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, block := range fetched {
+			blocks[block.Number] = block
+			tracking.Cache.Add(block)
+		}
+	}
+
+	return blocks, nil
+}
+
+func (tracking *TrackingEvent) fetchBlocks(numbers []int64) ([]database.Block, error) {
+	headers := make([]*types.Header, len(numbers))
+	batch := make([]rpc.BatchElem, len(numbers))
+	for i, number := range numbers {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.EncodeBig(big.NewInt(number)), false},
+			Result: &headers[i],
+		}
+	}
+
+	err := tracking.Pool.Call("eth_getBlockByNumber", len(batch), func(endpoint *Endpoint) error {
+		return endpoint.RpcClient.BatchCallContext(context.Background(), batch)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []database.Block
+	for i, elem := range batch {
+		if elem.Error != nil {
+			return nil, elem.Error
+		}
+		if headers[i] == nil {
+			return nil, fmt.Errorf("event: block %d not found", numbers[i])
+		}
+
+		blocks = append(blocks, database.Block{
+			Number:     headers[i].Number.Int64(),
+			Hash:       headers[i].Hash().String(),
+			ParentHash: headers[i].ParentHash.String(),
+			Time:       time.Unix(int64(headers[i].Time), 0),
+		})
+	}
+
+	return blocks, nil
+}
@@ -0,0 +1,210 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testContract = common.HexToAddress("0x0DF49Ee109bE77DA53d3050575e409D28D542ECC")
+
+// testDatabase connects to a migrated schema of its own in the Postgres
+// database of TEST_DATABASE_DSN and skips the test when there is none.
+func testDatabase(t *testing.T) *bun.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	setup := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn))), pgdialect.New())
+	defer setup.Close()
+
+	schema := fmt.Sprintf("event_test_%d", time.Now().UnixNano())
+	_, err := setup.ExecContext(context.Background(), "CREATE SCHEMA ?", bun.Ident(schema))
+	if err != nil {
+		t.Skipf("database is not available: %s", err)
+	}
+
+	db := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(
+		pgdriver.WithDSN(dsn),
+		pgdriver.WithConnParams(map[string]interface{}{"search_path": schema}),
+	)), pgdialect.New())
+
+	t.Cleanup(func() {
+		db.Close()
+
+		cleanup := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn))), pgdialect.New())
+		defer cleanup.Close()
+
+		_, err := cleanup.ExecContext(context.Background(), "DROP SCHEMA ? CASCADE", bun.Ident(schema))
+		if err != nil {
+			t.Errorf("drop schema %s: %s", schema, err)
+		}
+	})
+
+	err = database.MigrateToDb(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// testChain is the eth namespace of a node serving a chain of empty blocks.
+// Its logs are made by a function of the requested range, so that a test can
+// answer differently from one call to the next. Ranges of more than limit
+// blocks are rejected when limit is set.
+type testChain struct {
+	mu      sync.Mutex
+	headers []*types.Header
+	logs    func(from, to uint64) []types.Log
+	limit   uint64
+}
+
+type testFilter struct {
+	FromBlock hexutil.Uint64 `json:"fromBlock"`
+	ToBlock   hexutil.Uint64 `json:"toBlock"`
+}
+
+// newTestChain makes a chain from the genesis block up to head.
+func newTestChain(head int64) *testChain {
+	chain := &testChain{}
+	chain.fork(0, head, "a")
+	return chain
+}
+
+// fork replaces the blocks from number on with blocks up to head, whose extra
+// data makes their hashes differ from the replaced ones.
+func (chain *testChain) fork(number, head int64, extra string) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	chain.headers = chain.headers[:number]
+	for n := number; n <= head; n++ {
+		header := &types.Header{
+			Number:     big.NewInt(n),
+			Difficulty: big.NewInt(1),
+			Time:       uint64(1700000000 + n*3),
+			Extra:      []byte(extra),
+		}
+		if n > 0 {
+			header.ParentHash = chain.headers[n-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+}
+
+func (chain *testChain) hash(number int64) common.Hash {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	return chain.headers[number].Hash()
+}
+
+func (chain *testChain) GetBlockByNumber(number rpc.BlockNumber, full bool) (*types.Header, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	if number < 0 {
+		return chain.headers[len(chain.headers)-1], nil
+	}
+	if int(number) >= len(chain.headers) {
+		return nil, nil
+	}
+
+	return chain.headers[number], nil
+}
+
+func (chain *testChain) GetLogs(filter testFilter) ([]types.Log, error) {
+	if chain.limit > 0 && uint64(filter.ToBlock-filter.FromBlock)+1 > chain.limit {
+		return nil, errors.New("block range is too wide")
+	}
+	if chain.logs == nil {
+		return []types.Log{}, nil
+	}
+
+	logs := chain.logs(uint64(filter.FromBlock), uint64(filter.ToBlock))
+	if logs == nil {
+		logs = []types.Log{}
+	}
+	return logs, nil
+}
+
+// testEndpoint serves chain in process, with block ranges from min to max.
+func testEndpoint(t *testing.T, url string, chain *testChain, min, max int64) *Endpoint {
+	server := rpc.NewServer()
+	err := server.RegisterName("eth", chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	t.Cleanup(func() {
+		client.Close()
+		server.Stop()
+	})
+
+	return &Endpoint{
+		Url:       url,
+		Client:    ethclient.NewClient(client),
+		RpcClient: client,
+		Range:     NewBlockRange(min, max),
+	}
+}
+
+// testTracking tracks testContract on the given endpoints.
+func testTracking(t *testing.T, db *bun.DB, endpoints ...*Endpoint) *TrackingEvent {
+	contractAbi, err := LoadAbi("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &TrackingEvent{
+		Pool:    &RpcPool{ChainId: 56, Endpoints: endpoints},
+		ChainId: 56,
+		Address: testContract,
+		Abi:     contractAbi,
+		Cache:   NewBlockCache(0, db),
+	}
+}
+
+// testRequestLog is a RequestCreated log of testContract.
+func testRequestLog(t *testing.T, number uint64, hash common.Hash, requestId int64) types.Log {
+	contractAbi, err := LoadAbi("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := contractAbi.Events["RequestCreated"]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return types.Log{
+		Address: testContract,
+		Topics: []common.Hash{
+			event.ID,
+			common.BytesToHash(common.Address{1}.Bytes()),
+			common.BigToHash(big.NewInt(requestId)),
+		},
+		Data:        data,
+		BlockNumber: number,
+		BlockHash:   hash,
+		TxHash:      common.BigToHash(big.NewInt(requestId)),
+	}
+}
@@ -0,0 +1,175 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"os"
+)
+
+// ContractConfig describes a contract to index. Abi is the path of its ABI
+// JSON file, the embedded spinning wheel ABI is used when it is empty. Wheel
+// is the model its prize ids are checked against, they are not checked when
+// it is not set. SubscriptionId is the VRF subscription the contract pays its
+// requests with.
+type ContractConfig struct {
+	Address        string       `json:"address"`
+	FromBlock      int64        `json:"fromBlock"`
+	Abi            string       `json:"abi"`
+	Wheel          *WheelConfig `json:"wheel"`
+	SubscriptionId uint64       `json:"subscriptionId"`
+}
+
+// RpcConfig is an HTTP endpoint of a chain, given either as its url or as an
+// object. MinBlockRange and MaxBlockRange bound the FilterLogs ranges sent to
+// the endpoint, the defaults are used when MaxBlockRange is 0.
+type RpcConfig struct {
+	Url           string `json:"url"`
+	MinBlockRange int64  `json:"minBlockRange"`
+	MaxBlockRange int64  `json:"maxBlockRange"`
+}
+
+func (c *RpcConfig) UnmarshalJSON(data []byte) error {
+	var url string
+	if json.Unmarshal(data, &url) == nil {
+		*c = RpcConfig{Url: url}
+		return nil
+	}
+
+	type rpcConfig RpcConfig
+	return json.Unmarshal(data, (*rpcConfig)(c))
+}
+
+// ChainConfig describes a chain and the contracts indexed on it. Rpc lists the
+// HTTP endpoints of the chain, Ws is the optional websocket endpoint used by
+// the subscription mode. Coordinator is the address of the VRF coordinator
+// of the chain, its events are not tracked when it is empty. VerifyProofs
+// checks the proof of each fulfillment while indexing, ProvingKeys are the key
+// hashes of the oracles the proofs have to be made with. Without them a
+// correct proof is recorded as unverified-key, not as valid.
+type ChainConfig struct {
+	ChainId      int64       `json:"chainId"`
+	Rpc          []RpcConfig `json:"rpc"`
+	Ws           string      `json:"ws"`
+	Coordinator  string      `json:"coordinator"`
+	VerifyProofs bool        `json:"verifyProofs"`
+	ProvingKeys  []string    `json:"provingKeys"`
+	// Watchdog alerts the requests pending for too long when it is set.
+	Watchdog      *WatchdogConfig `json:"watchdog"`
+	Confirmations int64           `json:"confirmations"`
+	// RateLimit is the RPC budget in compute units per second, RateBurst the
+	// most that can be spent at once and MethodWeights the cost of a call per
+	// method. Calls are not throttled when RateLimit is 0.
+	RateLimit     float64            `json:"rateLimit"`
+	RateBurst     float64            `json:"rateBurst"`
+	MethodWeights map[string]float64 `json:"methodWeights"`
+	Contracts     []ContractConfig   `json:"contracts"`
+}
+
+func LoadChainConfig(path string) ([]ChainConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var chains []ChainConfig
+	err = json.Unmarshal(data, &chains)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chain := range chains {
+		if len(chain.Rpc) == 0 {
+			return nil, fmt.Errorf("event: chain %d has no rpc", chain.ChainId)
+		}
+
+		for _, rpc := range chain.Rpc {
+			if rpc.Url == "" {
+				return nil, fmt.Errorf("event: rpc without url on chain %d", chain.ChainId)
+			}
+		}
+
+		if chain.Coordinator != "" && !common.IsHexAddress(chain.Coordinator) {
+			return nil, fmt.Errorf("event: invalid coordinator address %q on chain %d", chain.Coordinator, chain.ChainId)
+		}
+
+		for _, contract := range chain.Contracts {
+			if !common.IsHexAddress(contract.Address) {
+				return nil, fmt.Errorf("event: invalid contract address %q on chain %d", contract.Address, chain.ChainId)
+			}
+		}
+	}
+
+	return chains, nil
+}
+
+// NewChainTracking connects to the RPC endpoints of a chain and checks that
+// they serve the configured chain id. The returned tracker has no contract,
+// use ForContract to index the contracts of the chain.
+func NewChainTracking(config ChainConfig) (*TrackingEvent, error) {
+	var urls []string
+	for _, rpc := range config.Rpc {
+		urls = append(urls, rpc.Url)
+	}
+
+	tracking, err := NewEventTracking(urls, "", config.Confirmations)
+	if err != nil {
+		return nil, err
+	}
+
+	if tracking.ChainId != config.ChainId {
+		return nil, fmt.Errorf("event: rpc serves chain %d, expected %d", tracking.ChainId, config.ChainId)
+	}
+
+	if config.Coordinator != "" {
+		tracking.Coordinator = common.HexToAddress(config.Coordinator)
+	}
+
+	if config.Watchdog != nil {
+		tracking.Watchdog, err = NewWatchdog(*config.Watchdog)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tracking.VerifyProofs = config.VerifyProofs
+	for _, key := range config.ProvingKeys {
+		tracking.ProvingKeys = append(tracking.ProvingKeys, common.HexToHash(key))
+	}
+
+	for _, endpoint := range tracking.Pool.Endpoints {
+		for _, rpc := range config.Rpc {
+			if rpc.Url == endpoint.Url && rpc.MaxBlockRange > 0 {
+				endpoint.Range = NewBlockRange(rpc.MinBlockRange, rpc.MaxBlockRange)
+			}
+		}
+	}
+
+	if config.RateLimit > 0 {
+		tracking.Pool.Limiter = NewRateLimiter(config.RateLimit, config.RateBurst, config.MethodWeights)
+	}
+	return tracking, nil
+}
+
+// ForContract returns a tracker for another contract that shares the RPC
+// pool, with the block range of each endpoint, and the header cache of
+// tracking.
+func (tracking *TrackingEvent) ForContract(config ContractConfig) (*TrackingEvent, error) {
+	contractAbi, err := LoadAbi(config.Abi)
+	if err != nil {
+		return nil, err
+	}
+
+	contract := *tracking
+	contract.Address = common.HexToAddress(config.Address)
+	contract.Abi = contractAbi
+	contract.SubscriptionId = config.SubscriptionId
+	contract.Wheel = nil
+	if config.Wheel != nil {
+		contract.Wheel, err = NewWheelModel(*config.Wheel)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &contract, nil
+}
@@ -0,0 +1,127 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+)
+
+//go:embed abi/consumer.json
+var consumerAbiJson []byte
+
+// consumerAbi is the callback every VRF consumer implements, parsed once.
+var consumerAbi, consumerAbiErr = abi.JSON(bytes.NewReader(consumerAbiJson))
+
+// replaySucceeded is the reason stored when the callback does not fail when
+// it is replayed, most likely because it ran out of gas in the coordinator.
+const replaySucceeded = "callback did not fail on replay"
+
+// failedRandom builds the failed spin of a fulfillment whose callback failed.
+// The gas used is the one of the whole fulfillment transaction, from its
+// receipt, and the reason is taken from replaying the callback on the state of
+// the previous block.
+func (tracking *TrackingEvent) failedRandom(vLog types.Log, fulfillment RandomWordsFulfilledEvent, commitment *RequestCommitment, block *database.Block) (*database.FailedRandom, error) {
+	var receipt *types.Receipt
+	err := tracking.Pool.Call("eth_getTransactionReceipt", 1, func(endpoint *Endpoint) error {
+		var err error
+		receipt, err = endpoint.Client.TransactionReceipt(context.Background(), vLog.TxHash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reason, err := tracking.replayCallback(vLog, fulfillment, commitment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &database.FailedRandom{
+		ChainId:     tracking.ChainId,
+		Contract:    tracking.Address.String(),
+		RequestId:   fulfillment.RequestId.String(),
+		TxGasUsed:   int64(receipt.GasUsed),
+		Reason:      reason,
+		TxHash:      vLog.TxHash.String(),
+		Index:       int(vLog.Index),
+		BlockNumber: block.Number,
+		BlockHash:   block.Hash,
+		Time:        block.Time,
+	}, nil
+}
+
+// replayCallback calls the consumer callback again with the random words of
+// the fulfillment and returns its revert reason. Errors are only returned
+// when no endpoint could be reached.
+func (tracking *TrackingEvent) replayCallback(vLog types.Log, fulfillment RandomWordsFulfilledEvent, commitment *RequestCommitment) (string, error) {
+	if commitment == nil {
+		return "", nil
+	}
+
+	if consumerAbiErr != nil {
+		return "", consumerAbiErr
+	}
+
+	data, err := consumerAbi.Pack("rawFulfillRandomWords", fulfillment.RequestId, RandomWords(fulfillment.OutputSeed, commitment.NumWords))
+	if err != nil {
+		return "", err
+	}
+
+	msg := ethereum.CallMsg{
+		From: tracking.Coordinator,
+		To:   &commitment.Sender,
+		Gas:  uint64(commitment.CallbackGasLimit),
+		Data: data,
+	}
+	number := new(big.Int).SetUint64(vLog.BlockNumber - 1)
+	err = tracking.Pool.Call("eth_call", 1, func(endpoint *Endpoint) error {
+		_, err := endpoint.Client.CallContract(context.Background(), msg, number)
+		return err
+	})
+	if err == nil {
+		return replaySucceeded, nil
+	}
+
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return "", err
+	}
+
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if hexData, ok := dataErr.ErrorData().(string); ok {
+			revert, decodeErr := hexutil.Decode(hexData)
+			if decodeErr == nil {
+				reason, unpackErr := abi.UnpackRevert(revert)
+				if unpackErr == nil {
+					return reason, nil
+				}
+			}
+		}
+	}
+
+	return err.Error(), nil
+}
+
+// RandomWords expands the output seed of a fulfillment into the random words
+// given to the callback, the same way the coordinator does.
+func RandomWords(outputSeed *big.Int, numWords uint32) []*big.Int {
+	words := make([]*big.Int, numWords)
+	seed := common.LeftPadBytes(outputSeed.Bytes(), 32)
+	for i := range words {
+		index := common.LeftPadBytes(big.NewInt(int64(i)).Bytes(), 32)
+		words[i] = new(big.Int).SetBytes(crypto.Keccak256(seed, index))
+	}
+
+	return words
+}
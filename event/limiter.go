@@ -0,0 +1,157 @@
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultWeights are the compute units charged by most providers for the
+// methods used by the tracker. Methods without a weight cost 1.
+var defaultWeights = map[string]float64{
+	"eth_getLogs":               75,
+	"eth_getBlockByNumber":      16,
+	"eth_getTransactionReceipt": 15,
+	"eth_getTransactionByHash":  15,
+	"eth_call":                  26,
+	"eth_blockNumber":           10,
+	"eth_chainId":               0,
+}
+
+// RateLimiter is a token bucket shared by every RPC call of a pool. Each call
+// takes the weight of its method from the bucket, which refills at Rate units
+// per second up to Burst.
+type RateLimiter struct {
+	Rate    float64
+	Burst   float64
+	Weights map[string]float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	start  time.Time
+	used   map[string]float64
+	calls  map[string]int64
+	waited time.Duration
+}
+
+// LimiterMetrics reports how much of the budget has been used since the
+// limiter was created.
+type LimiterMetrics struct {
+	Rate        float64            `json:"rate"`
+	Burst       float64            `json:"burst"`
+	Available   float64            `json:"available"`
+	Used        map[string]float64 `json:"used"`
+	Calls       map[string]int64   `json:"calls"`
+	TotalUsed   float64            `json:"totalUsed"`
+	Utilization float64            `json:"utilization"`
+	Waited      time.Duration      `json:"waited"`
+}
+
+func NewRateLimiter(rate, burst float64, weights map[string]float64) *RateLimiter {
+	if burst < rate {
+		burst = rate
+	}
+
+	methodWeights := make(map[string]float64)
+	for method, weight := range defaultWeights {
+		methodWeights[method] = weight
+	}
+	for method, weight := range weights {
+		methodWeights[method] = weight
+	}
+
+	now := time.Now()
+	return &RateLimiter{
+		Rate:    rate,
+		Burst:   burst,
+		Weights: methodWeights,
+		tokens:  burst,
+		last:    now,
+		start:   now,
+		used:    make(map[string]float64),
+		calls:   make(map[string]int64),
+	}
+}
+
+func (l *RateLimiter) weight(method string) float64 {
+	weight, ok := l.Weights[method]
+	if !ok {
+		return 1
+	}
+	return weight
+}
+
+// Wait blocks until the budget allows calls requests of method.
+func (l *RateLimiter) Wait(method string, calls int) {
+	if l == nil {
+		return
+	}
+
+	cost := l.weight(method) * float64(calls)
+	l.mu.Lock()
+	l.used[method] = l.used[method] + cost
+	l.calls[method] = l.calls[method] + int64(calls)
+	if l.Rate <= 0 {
+		l.mu.Unlock()
+		return
+	}
+
+	// a request bigger than the bucket would never fit, it only has to wait
+	// for a full bucket
+	if cost > l.Burst {
+		cost = l.Burst
+	}
+
+	for {
+		now := time.Now()
+		l.tokens = l.tokens + now.Sub(l.last).Seconds()*l.Rate
+		if l.tokens > l.Burst {
+			l.tokens = l.Burst
+		}
+		l.last = now
+
+		if l.tokens >= cost {
+			l.tokens = l.tokens - cost
+			l.mu.Unlock()
+			return
+		}
+
+		delay := time.Duration((cost - l.tokens) / l.Rate * float64(time.Second))
+		l.waited = l.waited + delay
+		l.mu.Unlock()
+		time.Sleep(delay)
+		l.mu.Lock()
+	}
+}
+
+// Metrics returns the usage of the budget, it is served by GET /metrics.
+func (l *RateLimiter) Metrics() LimiterMetrics {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	metrics := LimiterMetrics{
+		Rate:   l.Rate,
+		Burst:  l.Burst,
+		Used:   make(map[string]float64),
+		Calls:  make(map[string]int64),
+		Waited: l.waited,
+	}
+
+	available := l.tokens + time.Since(l.last).Seconds()*l.Rate
+	if available > l.Burst {
+		available = l.Burst
+	}
+	metrics.Available = available
+
+	for method, used := range l.used {
+		metrics.Used[method] = used
+		metrics.TotalUsed = metrics.TotalUsed + used
+	}
+	for method, calls := range l.calls {
+		metrics.Calls[method] = calls
+	}
+
+	budget := l.Burst + time.Since(l.start).Seconds()*l.Rate
+	metrics.Utilization = metrics.TotalUsed / budget
+	return metrics
+}
@@ -0,0 +1,62 @@
+package event
+
+import (
+	"testing"
+)
+
+func TestRateLimiterWeights(t *testing.T) {
+	limiter := NewRateLimiter(0, 0, map[string]float64{
+		"eth_getLogs":  100,
+		"eth_estimate": 3,
+	})
+
+	tests := []struct {
+		method string
+		calls  int
+		used   float64
+	}{
+		{"eth_getLogs", 2, 200},
+		{"eth_getBlockByNumber", 10, 160},
+		{"eth_getTransactionReceipt", 1, 15},
+		{"eth_call", 1, 26},
+		{"eth_chainId", 5, 0},
+		{"eth_estimate", 2, 6},
+		{"eth_gasPrice", 4, 4},
+	}
+
+	for _, test := range tests {
+		limiter.Wait(test.method, test.calls)
+	}
+
+	metrics := limiter.Metrics()
+	var total float64
+	for _, test := range tests {
+		if metrics.Used[test.method] != test.used {
+			t.Errorf("%s used %v, want %v", test.method, metrics.Used[test.method], test.used)
+		}
+		if metrics.Calls[test.method] != int64(test.calls) {
+			t.Errorf("%s made %d calls, want %d", test.method, metrics.Calls[test.method], test.calls)
+		}
+		total = total + test.used
+	}
+
+	if metrics.TotalUsed != total {
+		t.Errorf("total used %v, want %v", metrics.TotalUsed, total)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	limiter := NewRateLimiter(1000, 1000, map[string]float64{"eth_getLogs": 510})
+
+	// the bucket starts full, so the first call does not wait
+	limiter.Wait("eth_getLogs", 1)
+	if limiter.Metrics().Waited != 0 {
+		t.Errorf("waited %s with a full bucket", limiter.Metrics().Waited)
+	}
+
+	// the second call is 20 units short, about 20ms at 1000 units/s
+	limiter.Wait("eth_getLogs", 1)
+	if limiter.Metrics().Waited <= 0 {
+		t.Errorf("did not wait with an empty bucket")
+	}
+}
@@ -3,153 +3,401 @@ package event
 import (
 	"VRFChainlink/database"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/uptrace/bun"
 	"math/big"
 	"time"
 )
 
 type TrackingEvent struct {
-	Client  *ethclient.Client
+	Pool    *RpcPool
+	ChainId int64
 	Address common.Address
+	Abi     *abi.ABI
+	// Coordinator is the VRF coordinator the contract requests random words
+	// from, its events are not tracked when it is the zero address.
+	Coordinator    common.Address
+	CoordinatorAbi *abi.ABI
+	// VerifyProofs checks the VRF proof of every fulfillment while indexing.
+	// A proof is only valid when it was made with one of ProvingKeys.
+	VerifyProofs bool
+	ProvingKeys  []common.Hash
+	// Wheel re-derives the prize ids of the contract, they are not checked
+	// when it is nil.
+	Wheel WheelModel
+	// Watchdog alerts the requests left without a response, it is not run
+	// when nil.
+	Watchdog *Watchdog
+	// SubscriptionId is the VRF subscription the contract pays its requests
+	// with, its ledger is not tracked when it is 0.
+	SubscriptionId uint64
+	// Confirmations is the number of blocks an event has to be buried under
+	// before it is flagged as final.
+	Confirmations int64
+	Cache         *BlockCache
 }
 
-var (
-	requestCreatedHash  = crypto.Keccak256Hash([]byte("RequestCreated(address,uint256,uint256)")).Hex()
-	responseCreatedHash = crypto.Keccak256Hash([]byte("ResponseCreated(address,uint256,uint256[])")).Hex()
+const (
+	blockRange = 5000
+	headDelay  = 60 * time.Second
 )
 
-func NewEventTracking(rpc, address string) (*TrackingEvent, error) {
-	client, err := ethclient.Dial(rpc)
+// reorgDelay is how long the tracker waits for the nodes to settle on the new
+// chain after a log turned out to be reorganized away.
+var reorgDelay = 5 * time.Second
+
+func NewEventTracking(rpcUrls []string, address string, confirmations int64) (*TrackingEvent, error) {
+	pool, err := NewRpcPool(rpcUrls)
+	if err != nil {
+		return nil, err
+	}
+
+	contractAbi, err := LoadAbi("")
+	if err != nil {
+		return nil, err
+	}
+
+	coordinatorAbi, err := LoadCoordinatorAbi()
 	if err != nil {
 		return nil, err
 	}
 
 	addr := common.HexToAddress(address)
 	tracking := &TrackingEvent{
-		Client:  client,
-		Address: addr,
+		Pool:           pool,
+		ChainId:        pool.ChainId,
+		Address:        addr,
+		Abi:            contractAbi,
+		CoordinatorAbi: coordinatorAbi,
+		Confirmations:  confirmations,
+		Cache:          NewBlockCache(defaultCacheSize, nil),
 	}
 
 	return tracking, nil
 }
 
-func (tracking *TrackingEvent) GetEventFromBlockNumber(db *bun.DB, number *big.Int) {
-	for i := number.Int64(); ; i = i + 5000 {
-		blockNumber := big.NewInt(i)
+// GetEventFromBlockNumber indexes the contract events from the given block,
+// or from the block after the stored checkpoint when the contract has already
+// been indexed further than that.
+func (tracking *TrackingEvent) GetEventFromBlockNumber(db *bun.DB, number *big.Int) error {
+	fromBlock, err := tracking.startBlock(db, number)
+	if err != nil {
+		return err
+	}
 
-		req, res, err := tracking.GetEventByBlockNumber(blockNumber)
-		if err != nil {
-			fmt.Println(blockNumber, err)
-			err = database.InsertBlockErrorToDb(db, int(blockNumber.Int64()))
-			if err != nil {
-				fmt.Println("insert block error to db:", err)
-			}
+	for {
+		next, err := tracking.indexNextRange(db, fromBlock)
+		caughtUp := next == fromBlock
+		fromBlock = next
+		if errors.Is(err, ErrReorg) {
+			fmt.Println(fromBlock, err)
+			time.Sleep(reorgDelay)
 			continue
 		}
-
-		err = database.InsertRequestRandomToDb(db, req)
 		if err != nil {
-			fmt.Println("insert request to db:", err)
-			err = database.InsertBlockErrorToDb(db, int(blockNumber.Int64()))
-			if err != nil {
-				fmt.Println("insert block error to db:", err)
-			}
+			fmt.Println(fromBlock, err)
+			time.Sleep(headDelay)
 			continue
 		}
 
-		err = database.InsertResponseRandomToDb(db, res)
+		if caughtUp {
+			time.Sleep(headDelay)
+		}
+	}
+}
+
+func (tracking *TrackingEvent) startBlock(db *bun.DB, number *big.Int) (int64, error) {
+	fromBlock := number.Int64()
+	checkpoint, err := database.GetCheckpointFromDb(db, tracking.ChainId, tracking.Address.String())
+	if err != nil {
+		return 0, err
+	}
+
+	if checkpoint != nil && checkpoint.Block >= fromBlock {
+		fromBlock = checkpoint.Block + 1
+		fmt.Println("resume from checkpoint:", fromBlock)
+	}
+
+	return fromBlock, nil
+}
+
+// indexNextRange indexes the next block range starting at fromBlock and
+// returns the block to continue from, which is fromBlock itself once the
+// chain head has been reached. The block is returned with the error too, as a
+// rollback moves it back before the range fails.
+func (tracking *TrackingEvent) indexNextRange(db *bun.DB, fromBlock int64) (int64, error) {
+	lastestBlockNumber, finalBlock, err := tracking.finalizeEvents(db)
+	if err != nil {
+		return fromBlock, err
+	}
+
+	if fromBlock > lastestBlockNumber.Int64() {
+		return fromBlock, nil
+	}
+
+	ancestor, reorg, err := tracking.CheckReorg(db, fromBlock-1)
+	if err != nil {
+		return fromBlock, err
+	}
+
+	if reorg {
+		fmt.Println("reorg detected, rollback to block:", ancestor)
+		err = database.RollbackToDb(db, tracking.ChainId, tracking.Address.String(), tracking.SubscriptionId, ancestor)
 		if err != nil {
-			err = database.InsertBlockErrorToDb(db, int(blockNumber.Int64()))
-			if err != nil {
-				fmt.Println("insert block error to db:", err)
-			}
-			fmt.Println("insert response to db:", err)
+			return fromBlock, err
 		}
+		fromBlock = ancestor + 1
+	}
+
+	events, toBlock, err := tracking.getEventByAdaptiveRange(fromBlock, lastestBlockNumber.Int64())
+	if err == nil {
+		err = tracking.resolveFailed(db, events)
+	}
+	if errors.Is(err, ErrReorg) {
+		return fromBlock, err
+	}
+	if err != nil {
+		fmt.Println(fromBlock, err)
+		return tracking.skipRange(db, fromBlock, toBlock, err)
+	}
 
-		fmt.Println(i)
+	setFinal(events, finalBlock)
+	err = database.InsertEventsToDb(db, tracking.ChainId, tracking.Address.String(), toBlock, events)
+	if err != nil {
+		fmt.Println("insert events to db:", err)
+		return tracking.skipRange(db, fromBlock, toBlock, err)
 	}
+
+	fmt.Println(toBlock)
+	return toBlock + 1, nil
 }
 
-func (tracking *TrackingEvent) GetEventByBlockNumber(number *big.Int) ([]database.RequestRandom, []database.ResponseRandom, error) {
+// finalizeEvents flags the stored events buried under Confirmations blocks as
+// final and returns the latest block and the last final block.
+func (tracking *TrackingEvent) finalizeEvents(db *bun.DB) (*big.Int, int64, error) {
 	lastestBlockNumber, err := tracking.GetLatestBlockNumber()
 	if err != nil {
-		return nil, nil, err
+		return nil, 0, err
+	}
+
+	finalBlock := lastestBlockNumber.Int64() - tracking.Confirmations
+	err = database.FinalizeEventsToDb(db, tracking.ChainId, finalBlock)
+	if err != nil {
+		fmt.Println("finalize events:", err)
+	}
+
+	return lastestBlockNumber, finalBlock, nil
+}
+
+// skipRange leaves a range that failed to RetryBlockError and returns the
+// block to continue from. The range is indexed again when it cannot even be
+// recorded, so that it is never skipped without a trace.
+func (tracking *TrackingEvent) skipRange(db *bun.DB, fromBlock, toBlock int64, rangeErr error) (int64, error) {
+	err := database.SkipRangeToDb(db, tracking.ChainId, tracking.Address.String(), fromBlock, toBlock, rangeErr.Error())
+	if err != nil {
+		return fromBlock, fmt.Errorf("insert block error to db: %w", err)
+	}
+
+	return toBlock + 1, nil
+}
+
+func setFinal(events *database.RangeEvents, finalBlock int64) {
+	for i := range events.Request {
+		events.Request[i].Final = events.Request[i].BlockNumber <= finalBlock
+	}
+	for i := range events.Response {
+		events.Response[i].Final = events.Response[i].BlockNumber <= finalBlock
+	}
+	for i := range events.VrfRequest {
+		events.VrfRequest[i].Final = events.VrfRequest[i].BlockNumber <= finalBlock
+	}
+	for i := range events.VrfFulfillment {
+		events.VrfFulfillment[i].Final = events.VrfFulfillment[i].BlockNumber <= finalBlock
+	}
+	for i := range events.Failed {
+		events.Failed[i].Final = events.Failed[i].BlockNumber <= finalBlock
+	}
+	for i := range events.Subscription {
+		events.Subscription[i].Final = events.Subscription[i].BlockNumber <= finalBlock
+	}
+}
+
+// GetEventByBlockRange returns the contract events, and the VRF coordinator
+// events of the contract requests, between from and to, both inclusive,
+// together with the headers of the blocks they were found in and of the last
+// block of the range. ErrReorg is returned when a log does not belong to the
+// canonical chain anymore. The failed fulfillments of requests sent before the
+// range are left in Unresolved for resolveFailed.
+func (tracking *TrackingEvent) GetEventByBlockRange(from, to *big.Int) (*database.RangeEvents, error) {
+	logs, err := tracking.filterLogs(tracking.contractQuery(from, to))
+	if err != nil {
+		return nil, err
 	}
 
-	query := ethereum.FilterQuery{
-		FromBlock: number,
-		ToBlock:   new(big.Int).SetInt64(number.Int64() + 5000),
+	return tracking.rangeEvents(from, to, logs)
+}
+
+func (tracking *TrackingEvent) contractQuery(from, to *big.Int) ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		FromBlock: from,
+		ToBlock:   to,
 		Addresses: []common.Address{
 			tracking.Address,
 		},
 	}
+}
 
-	if number.Int64()+5000 > lastestBlockNumber.Int64() {
-		timeDelay := 60
-		time.Sleep(time.Duration(timeDelay) * time.Second)
-		query = ethereum.FilterQuery{
-			FromBlock: number,
-			ToBlock:   nil,
-			Addresses: []common.Address{
-				tracking.Address,
-			},
-		}
+// rangeEvents is GetEventByBlockRange once the contract logs of the range have
+// been fetched.
+func (tracking *TrackingEvent) rangeEvents(from, to *big.Int, logs []types.Log) (*database.RangeEvents, error) {
+	vrfLogs, failedLogs, err := tracking.filterVrfLogs(from, to, logs)
+	if err != nil {
+		return nil, err
+	}
+	logs = append(logs, vrfLogs...)
+
+	var numbers []int64
+	for _, vLog := range logs {
+		numbers = append(numbers, int64(vLog.BlockNumber))
 	}
 
-	logs, err := tracking.Client.FilterLogs(context.Background(), query)
+	headers, err := tracking.GetBlocks(numbers)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	var request []database.RequestRandom
-	var response []database.ResponseRandom
+	blocks := make(map[int64]database.Block)
+	events := &database.RangeEvents{}
+	for _, vLog := range failedLogs {
+		events.Unresolved = append(events.Unresolved, database.VrfFulfillment{
+			RequestId:   vLog.Topics[1].Big().String(),
+			TxHash:      vLog.TxHash.String(),
+			Index:       int(vLog.Index),
+			BlockNumber: int64(vLog.BlockNumber),
+			BlockHash:   vLog.BlockHash.String(),
+		})
+	}
 	for _, vLog := range logs {
-		switch vLog.Topics[0].Hex() {
-		case requestCreatedHash:
-			timeStamp, err := tracking.GetTimeOfBlock(big.NewInt(int64(vLog.BlockNumber)))
-			if err != nil {
-				return nil, nil, err
-			}
-
-			request = append(request, database.RequestRandom{
-				User:      common.HexToAddress(vLog.Topics[1].Hex()).String(),
-				RequestId: new(big.Int).SetBytes(vLog.Topics[2].Bytes()).String(),
-				Amount:    int(new(big.Int).SetBytes(vLog.Data).Int64()),
-				TxHash:    vLog.TxHash.String(),
-				Index:     int(vLog.Index),
-				Time:      timeStamp,
-			})
-		case responseCreatedHash:
-			var prizeIds []int
-			for i := 64; i+32 < len(vLog.Data); i = i + 32 {
-				prizeIds = append(prizeIds, int(new(big.Int).SetBytes(vLog.Data[i:i+32]).Int64()))
-			}
-
-			timeStamp, err := tracking.GetTimeOfBlock(big.NewInt(int64(vLog.BlockNumber)))
-			if err != nil {
-				return nil, nil, err
-			}
-
-			response = append(response, database.ResponseRandom{
-				User:      common.HexToAddress(vLog.Topics[1].Hex()).String(),
-				RequestId: new(big.Int).SetBytes(vLog.Topics[2].Bytes()).String(),
-				PrizeIds:  prizeIds,
-				TxHash:    vLog.TxHash.String(),
-				Index:     int(vLog.Index),
-				Time:      timeStamp,
-			})
+		if vLog.Removed || len(vLog.Topics) == 0 {
+			continue
+		}
+
+		block, err := tracking.canonicalBlock(headers[int64(vLog.BlockNumber)], vLog.BlockHash.String())
+		if err != nil {
+			return nil, err
+		}
+		headers[block.Number] = *block
+		block.ChainId = tracking.ChainId
+		block.Contract = tracking.Address.String()
+		blocks[block.Number] = *block
+
+		if vLog.Address == tracking.Coordinator {
+			err = tracking.decodeVrfLog(events, vLog, block)
+		} else {
+			err = tracking.decodeLog(events, vLog, block)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	last, err := tracking.GetBlock(to)
+	if err != nil {
+		return nil, err
+	}
+	tracking.Cache.Add(*last)
+	last.ChainId = tracking.ChainId
+	last.Contract = tracking.Address.String()
+	blocks[last.Number] = *last
+
+	for _, block := range blocks {
+		events.Blocks = append(events.Blocks, block)
+	}
+
+	return events, nil
+}
+
+// decodeLog appends the contract event of vLog to events. Events the tracker
+// does not store are skipped.
+func (tracking *TrackingEvent) decodeLog(events *database.RangeEvents, vLog types.Log, block *database.Block) error {
+	event, err := tracking.Abi.EventByID(vLog.Topics[0])
+	if err != nil {
+		return nil
+	}
+
+	switch event.Name {
+	case "RequestCreated":
+		var requestCreated RequestCreatedEvent
+		err = UnpackLog(tracking.Abi, &requestCreated, event.Name, vLog)
+		if err != nil {
+			return err
+		}
+
+		events.Request = append(events.Request, database.RequestRandom{
+			ChainId:     tracking.ChainId,
+			Contract:    tracking.Address.String(),
+			User:        requestCreated.User.String(),
+			RequestId:   requestCreated.RequestId.String(),
+			Amount:      database.NewBigInt(requestCreated.Amount),
+			TxHash:      vLog.TxHash.String(),
+			Index:       int(vLog.Index),
+			BlockNumber: block.Number,
+			BlockHash:   block.Hash,
+			Time:        block.Time,
+		})
+	case "ResponseCreated":
+		var responseCreated ResponseCreatedEvent
+		err = UnpackLog(tracking.Abi, &responseCreated, event.Name, vLog)
+		if err != nil {
+			return err
 		}
+
+		events.Response = append(events.Response, database.ResponseRandom{
+			ChainId:     tracking.ChainId,
+			Contract:    tracking.Address.String(),
+			User:        responseCreated.User.String(),
+			RequestId:   responseCreated.RequestId.String(),
+			PrizeIds:    database.BigInts(responseCreated.PrizeIds),
+			TxHash:      vLog.TxHash.String(),
+			Index:       int(vLog.Index),
+			BlockNumber: block.Number,
+			BlockHash:   block.Hash,
+			Time:        block.Time,
+		})
 	}
-	return request, response, nil
+
+	return nil
+}
+
+// canonicalBlock checks a cached header against the block hash of a log. A
+// stale header is evicted and fetched again, and ErrReorg is returned when the
+// log still does not belong to the canonical block.
+func (tracking *TrackingEvent) canonicalBlock(block database.Block, hash string) (*database.Block, error) {
+	if block.Hash == hash {
+		return &block, nil
+	}
+
+	tracking.Cache.Remove(block.Number)
+	fresh, err := tracking.GetBlock(big.NewInt(block.Number))
+	if err != nil {
+		return nil, err
+	}
+
+	if fresh.Hash != hash {
+		return nil, ErrReorg
+	}
+
+	tracking.Cache.Add(*fresh)
+	return fresh, nil
 }
 
 func (tracking *TrackingEvent) GetLatestBlockNumber() (*big.Int, error) {
-	header, err := tracking.Client.HeaderByNumber(context.Background(), nil)
+	header, err := tracking.headerByNumber(nil)
 	if err != nil {
 		return nil, err
 	}
@@ -157,8 +405,25 @@ func (tracking *TrackingEvent) GetLatestBlockNumber() (*big.Int, error) {
 	return header.Number, nil
 }
 
+func (tracking *TrackingEvent) headerByNumber(number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	err := tracking.Pool.Call("eth_getBlockByNumber", 1, func(endpoint *Endpoint) error {
+		var err error
+		header, err = endpoint.Client.HeaderByNumber(context.Background(), number)
+		if err == nil && number == nil {
+			endpoint.setHead(header.Number.Uint64())
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
 func (tracking *TrackingEvent) GetTimeOfBlock(block *big.Int) (time.Time, error) {
-	header, err := tracking.Client.HeaderByNumber(context.Background(), block)
+	header, err := tracking.headerByNumber(block)
 	if err != nil {
 		return time.Time{}, err
 	}
@@ -166,3 +431,17 @@ func (tracking *TrackingEvent) GetTimeOfBlock(block *big.Int) (time.Time, error)
 	timeStamp := time.Unix(int64(header.Time), 0)
 	return timeStamp, nil
 }
+
+func (tracking *TrackingEvent) GetBlock(number *big.Int) (*database.Block, error) {
+	header, err := tracking.headerByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &database.Block{
+		Number:     header.Number.Int64(),
+		Hash:       header.Hash().String(),
+		ParentHash: header.ParentHash.String(),
+		Time:       time.Unix(int64(header.Time), 0),
+	}, nil
+}
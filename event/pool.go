@@ -0,0 +1,264 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"sync"
+	"time"
+)
+
+const (
+	healthCheckDelay = 30 * time.Second
+	// maxFailures is the number of consecutive failed calls after which an
+	// endpoint is left out until unhealthyDelay has passed.
+	maxFailures    = 3
+	unhealthyDelay = time.Minute
+	// maxHeadLag is how far an endpoint may be behind the best known head
+	// before calls stop being switched to it.
+	maxHeadLag = 5
+)
+
+// Endpoint is a single RPC provider of a pool. Range is the FilterLogs range
+// of the endpoint, as each provider limits it differently.
+type Endpoint struct {
+	Url       string
+	Client    *ethclient.Client
+	RpcClient *rpc.Client
+	Range     *BlockRange
+
+	mu        sync.Mutex
+	latency   time.Duration
+	failures  int
+	downUntil time.Time
+	head      uint64
+}
+
+// EndpointStatus is a snapshot of the health of an endpoint.
+type EndpointStatus struct {
+	Url      string        `json:"url"`
+	Healthy  bool          `json:"healthy"`
+	Latency  time.Duration `json:"latency"`
+	Failures int           `json:"failures"`
+	Head     uint64        `json:"head"`
+}
+
+func (e *Endpoint) healthy(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.failures < maxFailures || now.After(e.downUntil)
+}
+
+func (e *Endpoint) record(latency time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err != nil {
+		e.failures++
+		if e.failures >= maxFailures {
+			e.downUntil = time.Now().Add(unhealthyDelay)
+		}
+		return
+	}
+
+	e.failures = 0
+	if e.latency == 0 {
+		e.latency = latency
+		return
+	}
+	// exponentially weighted moving average
+	e.latency = (e.latency*4 + latency) / 5
+}
+
+func (e *Endpoint) setHead(head uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.head = head
+}
+
+func (e *Endpoint) Status() EndpointStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return EndpointStatus{
+		Url:      e.Url,
+		Healthy:  e.failures < maxFailures || time.Now().After(e.downUntil),
+		Latency:  e.latency,
+		Failures: e.failures,
+		Head:     e.head,
+	}
+}
+
+// RpcPool spreads the RPC calls of a chain over several endpoints in a round
+// robin. A call that fails on an endpoint is retried on the next one, and an
+// endpoint is only switched to while its head agrees with the best known head.
+type RpcPool struct {
+	ChainId   int64
+	Endpoints []*Endpoint
+	// Limiter throttles the calls of every endpoint when it is set.
+	Limiter *RateLimiter
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewRpcPool dials every url and keeps the endpoints that answer with the
+// same chain id as the first reachable one.
+func NewRpcPool(urls []string) (*RpcPool, error) {
+	pool := &RpcPool{}
+	var err error
+	for _, url := range urls {
+		var rpcClient *rpc.Client
+		rpcClient, err = rpc.Dial(url)
+		if err != nil {
+			fmt.Println("dial rpc:", url, err)
+			continue
+		}
+
+		client := ethclient.NewClient(rpcClient)
+		chainId, chainErr := client.ChainID(context.Background())
+		if chainErr != nil {
+			err = chainErr
+			fmt.Println("get chain id:", url, err)
+			client.Close()
+			continue
+		}
+
+		if len(pool.Endpoints) > 0 && chainId.Int64() != pool.ChainId {
+			fmt.Println("rpc on another chain:", url, chainId)
+			client.Close()
+			continue
+		}
+
+		pool.ChainId = chainId.Int64()
+		pool.Endpoints = append(pool.Endpoints, &Endpoint{
+			Url:       url,
+			Client:    client,
+			RpcClient: rpcClient,
+			Range:     NewBlockRange(minBlockRange, blockRange),
+		})
+	}
+
+	if len(pool.Endpoints) == 0 {
+		if err == nil {
+			err = errors.New("event: no rpc endpoint")
+		}
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// Call runs fn, which sends calls requests of method, on the next usable
+// endpoint and fails over to the following ones when the endpoint itself
+// fails. Errors returned by the node as a JSON-RPC error are answers, not
+// failures, and are returned right away.
+func (pool *RpcPool) Call(method string, calls int, fn func(endpoint *Endpoint) error) error {
+	endpoints := pool.candidates()
+
+	var err error
+	for _, endpoint := range endpoints {
+		pool.Limiter.Wait(method, calls)
+		start := time.Now()
+		err = fn(endpoint)
+
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) {
+			endpoint.record(time.Since(start), nil)
+			return err
+		}
+
+		endpoint.record(time.Since(start), err)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// candidates returns the endpoints in the order they should be tried, the
+// healthy ones in sync with the best head first, starting from the next one in
+// the round robin.
+func (pool *RpcPool) candidates() []*Endpoint {
+	pool.mu.Lock()
+	start := pool.next
+	pool.next = (pool.next + 1) % len(pool.Endpoints)
+	pool.mu.Unlock()
+
+	bestHead := pool.bestHead()
+	now := time.Now()
+	var usable, others []*Endpoint
+	for i := range pool.Endpoints {
+		endpoint := pool.Endpoints[(start+i)%len(pool.Endpoints)]
+		status := endpoint.Status()
+		if endpoint.healthy(now) && status.Head+maxHeadLag >= bestHead {
+			usable = append(usable, endpoint)
+			continue
+		}
+		others = append(others, endpoint)
+	}
+
+	return append(usable, others...)
+}
+
+func (pool *RpcPool) bestHead() uint64 {
+	var best uint64
+	for _, endpoint := range pool.Endpoints {
+		status := endpoint.Status()
+		if status.Healthy && status.Head > best {
+			best = status.Head
+		}
+	}
+
+	return best
+}
+
+// CheckHealth asks every endpoint for its head, recording its latency, its
+// failures and how far it is behind the others.
+func (pool *RpcPool) CheckHealth() {
+	for _, endpoint := range pool.Endpoints {
+		pool.Limiter.Wait("eth_blockNumber", 1)
+		start := time.Now()
+		head, err := endpoint.Client.BlockNumber(context.Background())
+		endpoint.record(time.Since(start), err)
+		if err != nil {
+			fmt.Println("rpc health check:", endpoint.Url, err)
+			continue
+		}
+
+		endpoint.setHead(head)
+	}
+}
+
+func (pool *RpcPool) RunHealthCheck() {
+	for {
+		pool.CheckHealth()
+		time.Sleep(healthCheckDelay)
+	}
+}
+
+// maxBlockRange is the largest FilterLogs range of the endpoints.
+func (pool *RpcPool) maxBlockRange() int64 {
+	var max int64
+	for _, endpoint := range pool.Endpoints {
+		if endpoint.Range.Max > max {
+			max = endpoint.Range.Max
+		}
+	}
+
+	return max
+}
+
+func (pool *RpcPool) Status() []EndpointStatus {
+	var status []EndpointStatus
+	for _, endpoint := range pool.Endpoints {
+		status = append(status, endpoint.Status())
+	}
+
+	return status
+}
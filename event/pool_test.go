@@ -0,0 +1,98 @@
+package event
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPoolCallFailover(t *testing.T) {
+	transportErr := errors.New("dial tcp: connection refused")
+	nodeErr := &rpcError{code: -32000, message: "execution reverted"}
+
+	tests := []struct {
+		name     string
+		errs     map[string]error
+		err      error
+		called   []string
+		failures map[string]int
+	}{
+		{
+			name:     "first endpoint answers",
+			errs:     map[string]error{},
+			called:   []string{"a"},
+			failures: map[string]int{"a": 0, "b": 0, "c": 0},
+		},
+		{
+			name:     "fails over on a transport error",
+			errs:     map[string]error{"a": transportErr},
+			called:   []string{"a", "b"},
+			failures: map[string]int{"a": 1, "b": 0, "c": 0},
+		},
+		{
+			name:     "returns a node error right away",
+			errs:     map[string]error{"a": nodeErr},
+			err:      nodeErr,
+			called:   []string{"a"},
+			failures: map[string]int{"a": 0, "b": 0, "c": 0},
+		},
+		{
+			name:     "returns the last error when every endpoint fails",
+			errs:     map[string]error{"a": transportErr, "b": transportErr, "c": transportErr},
+			err:      transportErr,
+			called:   []string{"a", "b", "c"},
+			failures: map[string]int{"a": 1, "b": 1, "c": 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := &RpcPool{Endpoints: []*Endpoint{{Url: "a"}, {Url: "b"}, {Url: "c"}}}
+
+			var called []string
+			err := pool.Call("eth_blockNumber", 1, func(endpoint *Endpoint) error {
+				called = append(called, endpoint.Url)
+				return test.errs[endpoint.Url]
+			})
+			if !errors.Is(err, test.err) {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+
+			if len(called) != len(test.called) {
+				t.Fatalf("called %v, want %v", called, test.called)
+			}
+			for i := range called {
+				if called[i] != test.called[i] {
+					t.Fatalf("called %v, want %v", called, test.called)
+				}
+			}
+
+			for _, endpoint := range pool.Endpoints {
+				if endpoint.Status().Failures != test.failures[endpoint.Url] {
+					t.Errorf("%s has %d failures, want %d", endpoint.Url, endpoint.Status().Failures, test.failures[endpoint.Url])
+				}
+			}
+		})
+	}
+}
+
+func TestPoolCandidates(t *testing.T) {
+	pool := &RpcPool{Endpoints: []*Endpoint{{Url: "a"}, {Url: "b"}, {Url: "c"}}}
+	pool.Endpoints[0].setHead(100)
+	pool.Endpoints[1].setHead(90)
+	pool.Endpoints[2].setHead(100)
+	for i := 0; i < maxFailures; i++ {
+		pool.Endpoints[2].record(0, errors.New("timeout"))
+	}
+
+	// b lags behind the best head and c is down, so a is tried first on
+	// every turn of the round robin
+	for turn := 0; turn < 3; turn++ {
+		candidates := pool.candidates()
+		if candidates[0].Url != "a" {
+			t.Errorf("turn %d: %s is tried first", turn, candidates[0].Url)
+		}
+		if len(candidates) != 3 {
+			t.Errorf("turn %d: got %d candidates, want every endpoint", turn, len(candidates))
+		}
+	}
+}
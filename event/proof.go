@@ -0,0 +1,202 @@
+package event
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+)
+
+// The prefixes VRF.sol adds to the hashes of the proof, so that they are
+// domain separated.
+var (
+	hashToCurvePrefix     = big.NewInt(1)
+	scalarFromCurvePrefix = big.NewInt(2)
+	randomOutputPrefix    = big.NewInt(3)
+)
+
+var (
+	fieldSize, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	generatorX, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	generatorY, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+	// sqrtPower is (fieldSize + 1) / 4, the field size being 3 mod 4
+	sqrtPower = new(big.Int).Rsh(new(big.Int).Add(fieldSize, big.NewInt(1)), 2)
+)
+
+var (
+	ErrProofNotOnCurve = errors.New("event: proof point is not on curve")
+	ErrProofWitness    = errors.New("event: address of c*pk+s*g does not match uWitness")
+	ErrInvalidProof    = errors.New("event: invalid proof")
+)
+
+// Proof is the ECVRF proof sent to the coordinator in a fulfillRandomWords
+// transaction. The witnesses only make the on-chain check cheaper, the proof
+// is verified here without them.
+type Proof struct {
+	Pk            [2]*big.Int
+	Gamma         [2]*big.Int
+	C             *big.Int
+	S             *big.Int
+	Seed          *big.Int
+	UWitness      common.Address
+	CGammaWitness [2]*big.Int
+	SHashWitness  [2]*big.Int
+	ZInv          *big.Int
+}
+
+// point is an affine point of secp256k1, nil being the point at infinity.
+type point struct {
+	x *big.Int
+	y *big.Int
+}
+
+// VerifyProof checks the proof for seed, which is the preSeed of the request
+// mixed with the hash of its block, and returns the random output it proves,
+// following verifyVRFProof and randomValueFromVRFProof of VRF.sol.
+func VerifyProof(proof *Proof, seed *big.Int) (*big.Int, error) {
+	pk := &point{proof.Pk[0], proof.Pk[1]}
+	gamma := &point{proof.Gamma[0], proof.Gamma[1]}
+	if !onCurve(pk) || !onCurve(gamma) {
+		return nil, ErrProofNotOnCurve
+	}
+
+	generator := &point{generatorX, generatorY}
+	u := addPoints(mulPoint(proof.C, pk), mulPoint(proof.S, generator))
+	if u == nil || pointAddress(u) != proof.UWitness {
+		return nil, ErrProofWitness
+	}
+
+	hash := hashToCurve(pk, seed)
+	v := addPoints(mulPoint(proof.C, gamma), mulPoint(proof.S, hash))
+	if v == nil {
+		return nil, ErrInvalidProof
+	}
+
+	derivedC := new(big.Int).SetBytes(crypto.Keccak256(
+		word(scalarFromCurvePrefix),
+		word(hash.x), word(hash.y),
+		word(pk.x), word(pk.y),
+		word(gamma.x), word(gamma.y),
+		word(v.x), word(v.y),
+		proof.UWitness.Bytes(),
+	))
+	if derivedC.Cmp(proof.C) != 0 {
+		return nil, ErrInvalidProof
+	}
+
+	output := new(big.Int).SetBytes(crypto.Keccak256(word(randomOutputPrefix), word(gamma.x), word(gamma.y)))
+	return output, nil
+}
+
+// KeyHash returns the hash the coordinator registers a proving key under.
+func KeyHash(pk [2]*big.Int) common.Hash {
+	return crypto.Keccak256Hash(word(pk[0]), word(pk[1]))
+}
+
+// ProofSeed returns the seed a proof is made for, from the preSeed of the
+// request and the hash of the block it was sent in.
+func ProofSeed(preSeed *big.Int, blockHash common.Hash) *big.Int {
+	return new(big.Int).SetBytes(crypto.Keccak256(word(preSeed), blockHash.Bytes()))
+}
+
+// RequestId returns the request id the coordinator derives from a key hash
+// and a preSeed.
+func RequestId(keyHash common.Hash, preSeed *big.Int) *big.Int {
+	return new(big.Int).SetBytes(crypto.Keccak256(keyHash.Bytes(), word(preSeed)))
+}
+
+func hashToCurve(pk *point, seed *big.Int) *point {
+	candidate := curveCandidate(crypto.Keccak256(word(hashToCurvePrefix), word(pk.x), word(pk.y), word(seed)))
+	for !onCurve(candidate) {
+		candidate = curveCandidate(crypto.Keccak256(word(candidate.x)))
+	}
+
+	return candidate
+}
+
+// curveCandidate maps a hash to a field element x and the even root of
+// x^3+7, which is a point of the curve only when x^3+7 is a square.
+func curveCandidate(hash []byte) *point {
+	x := new(big.Int).SetBytes(hash)
+	for x.Cmp(fieldSize) >= 0 {
+		x = new(big.Int).SetBytes(crypto.Keccak256(word(x)))
+	}
+
+	y := new(big.Int).Exp(ySquared(x), sqrtPower, fieldSize)
+	if y.Bit(0) == 1 {
+		y.Sub(fieldSize, y)
+	}
+
+	return &point{x, y}
+}
+
+func ySquared(x *big.Int) *big.Int {
+	xCubed := new(big.Int).Exp(x, big.NewInt(3), fieldSize)
+	return xCubed.Add(xCubed, big.NewInt(7)).Mod(xCubed, fieldSize)
+}
+
+func onCurve(p *point) bool {
+	if p == nil || p.x.Sign() <= 0 || p.x.Cmp(fieldSize) >= 0 || p.y.Sign() <= 0 || p.y.Cmp(fieldSize) >= 0 {
+		return false
+	}
+
+	ySquare := new(big.Int).Exp(p.y, big.NewInt(2), fieldSize)
+	return ySquare.Cmp(ySquared(p.x)) == 0
+}
+
+func addPoints(p, q *point) *point {
+	if p == nil {
+		return q
+	}
+	if q == nil {
+		return p
+	}
+
+	var slope *big.Int
+	if p.x.Cmp(q.x) == 0 {
+		sum := new(big.Int).Add(p.y, q.y)
+		if sum.Mod(sum, fieldSize).Sign() == 0 {
+			return nil
+		}
+
+		// tangent of a doubled point: 3x^2 / 2y
+		numerator := new(big.Int).Mul(big.NewInt(3), new(big.Int).Mul(p.x, p.x))
+		denominator := new(big.Int).Lsh(p.y, 1)
+		slope = numerator.Mul(numerator, denominator.ModInverse(denominator, fieldSize))
+	} else {
+		numerator := new(big.Int).Sub(q.y, p.y)
+		denominator := new(big.Int).Sub(q.x, p.x)
+		denominator.Mod(denominator, fieldSize)
+		slope = numerator.Mul(numerator, denominator.ModInverse(denominator, fieldSize))
+	}
+	slope.Mod(slope, fieldSize)
+
+	x := new(big.Int).Mul(slope, slope)
+	x.Sub(x, p.x).Sub(x, q.x).Mod(x, fieldSize)
+	y := new(big.Int).Sub(p.x, x)
+	y.Mul(y, slope).Sub(y, p.y).Mod(y, fieldSize)
+	return &point{x, y}
+}
+
+func mulPoint(k *big.Int, p *point) *point {
+	var result *point
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = addPoints(result, result)
+		if k.Bit(i) == 1 {
+			result = addPoints(result, p)
+		}
+	}
+
+	return result
+}
+
+// pointAddress is the address of the public key p, the way ecrecover
+// derives it.
+func pointAddress(p *point) common.Address {
+	return common.BytesToAddress(crypto.Keccak256(word(p.x), word(p.y))[12:])
+}
+
+// word is the 32 bytes big endian encoding of a uint256, as in abi.encode.
+func word(value *big.Int) []byte {
+	return common.LeftPadBytes(value.Bytes(), 32)
+}
@@ -0,0 +1,153 @@
+package event
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+)
+
+// testProof makes the proof of seed with the secret key sk and the nonce k,
+// the way the VRF oracle does, with the curve of go-ethereum rather than the
+// arithmetic under test.
+func testProof(sk, k, seed *big.Int) *Proof {
+	curve := crypto.S256()
+	pkX, pkY := curve.ScalarBaseMult(word(sk))
+	pk := &point{pkX, pkY}
+
+	hash := hashToCurve(pk, seed)
+	gammaX, gammaY := curve.ScalarMult(hash.x, hash.y, word(sk))
+	uX, uY := curve.ScalarBaseMult(word(k))
+	vX, vY := curve.ScalarMult(hash.x, hash.y, word(k))
+	uWitness := pointAddress(&point{uX, uY})
+
+	c := new(big.Int).SetBytes(crypto.Keccak256(
+		word(scalarFromCurvePrefix),
+		word(hash.x), word(hash.y),
+		word(pkX), word(pkY),
+		word(gammaX), word(gammaY),
+		word(vX), word(vY),
+		uWitness.Bytes(),
+	))
+	s := new(big.Int).Mul(c, sk)
+	s.Sub(k, s).Mod(s, curve.Params().N)
+
+	return &Proof{
+		Pk:       [2]*big.Int{pkX, pkY},
+		Gamma:    [2]*big.Int{gammaX, gammaY},
+		C:        c,
+		S:        s,
+		Seed:     seed,
+		UWitness: uWitness,
+	}
+}
+
+func testKey() (sk, k *big.Int) {
+	sk, _ = new(big.Int).SetString("3c8b0f6a2d91e7c4b5a6f0e1d2c3b4a5968778695a4b3c2d1e0f1a2b3c4d5e6f", 16)
+	k, _ = new(big.Int).SetString("1f2e3d4c5b6a79880f1e2d3c4b5a69788796a5b4c3d2e1f00112233445566778", 16)
+	return sk, k
+}
+
+func TestVerifyProof(t *testing.T) {
+	sk, k := testKey()
+	seed := big.NewInt(987654321)
+	proof := testProof(sk, k, seed)
+
+	curve := crypto.S256()
+	gammaX, gammaY := curve.Add(proof.Gamma[0], proof.Gamma[1], generatorX, generatorY)
+
+	tests := []struct {
+		name   string
+		tamper func(proof *Proof)
+		seed   *big.Int
+		err    error
+	}{
+		{
+			name:   "valid",
+			tamper: func(proof *Proof) {},
+			seed:   seed,
+		},
+		{
+			name: "gamma off curve",
+			tamper: func(proof *Proof) {
+				proof.Gamma[0] = new(big.Int).Add(proof.Gamma[0], big.NewInt(1))
+			},
+			seed: seed,
+			err:  ErrProofNotOnCurve,
+		},
+		{
+			name: "other gamma on curve",
+			tamper: func(proof *Proof) {
+				proof.Gamma = [2]*big.Int{gammaX, gammaY}
+			},
+			seed: seed,
+			err:  ErrInvalidProof,
+		},
+		{
+			name: "c",
+			tamper: func(proof *Proof) {
+				proof.C = new(big.Int).Add(proof.C, big.NewInt(1))
+			},
+			seed: seed,
+			err:  ErrProofWitness,
+		},
+		{
+			name: "s",
+			tamper: func(proof *Proof) {
+				proof.S = new(big.Int).Add(proof.S, big.NewInt(1))
+			},
+			seed: seed,
+			err:  ErrProofWitness,
+		},
+		{
+			name:   "seed",
+			tamper: func(proof *Proof) {},
+			seed:   new(big.Int).Add(seed, big.NewInt(1)),
+			err:    ErrInvalidProof,
+		},
+		{
+			name: "uWitness",
+			tamper: func(proof *Proof) {
+				proof.UWitness = common.Address{1}
+			},
+			seed: seed,
+			err:  ErrProofWitness,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tampered := *proof
+			test.tamper(&tampered)
+
+			output, err := VerifyProof(&tampered, test.seed)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+			if err != nil {
+				return
+			}
+
+			want := new(big.Int).SetBytes(crypto.Keccak256(word(randomOutputPrefix), word(proof.Gamma[0]), word(proof.Gamma[1])))
+			if output.Cmp(want) != 0 {
+				t.Errorf("got output %s, want %s", output, want)
+			}
+		})
+	}
+}
+
+func TestRandomWords(t *testing.T) {
+	outputSeed := big.NewInt(42)
+	words := RandomWords(outputSeed, 2)
+	if len(words) != 2 {
+		t.Fatalf("got %d words, want 2", len(words))
+	}
+
+	for i, got := range words {
+		want := new(big.Int).SetBytes(crypto.Keccak256(word(outputSeed), word(big.NewInt(int64(i)))))
+		if got.Cmp(want) != 0 {
+			t.Errorf("word %d is %s, want %s", i, got, want)
+		}
+	}
+}
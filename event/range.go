@@ -0,0 +1,187 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+const (
+	minBlockRange = 10
+	// growLogs is the number of events below which a range is considered
+	// small enough to try a bigger one next time.
+	growLogs = 1000
+)
+
+var errRangeShrunk = errors.New("event: block range shrunk")
+
+// rangeLimitErrors are the messages RPC providers answer with when a
+// FilterLogs range returns too many results or spans too many blocks. They
+// name the size of the range, so that errors about a range that is only
+// wrong, like one past the head, or about the rate limit do not shrink it.
+var rangeLimitErrors = []string{
+	"too many results",
+	"query returned more than",
+	"block range is too",
+	"block range too",
+	"range limit exceeded",
+	"exceed maximum block range",
+	"response size exceeded",
+	"response size limit",
+	"query timeout",
+}
+
+// BlockRange is the number of blocks requested per FilterLogs call. It is
+// halved when the provider rejects a range and doubled while the ranges stay
+// small, always within Min and Max.
+type BlockRange struct {
+	Min int64
+	Max int64
+
+	mu   sync.Mutex
+	size int64
+}
+
+func NewBlockRange(min, max int64) *BlockRange {
+	if min <= 0 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+
+	return &BlockRange{
+		Min:  min,
+		Max:  max,
+		size: max,
+	}
+}
+
+func (r *BlockRange) Size() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.size
+}
+
+// Shrink halves the range size and returns false when it is already at Min.
+func (r *BlockRange) Shrink() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size <= r.Min {
+		return false
+	}
+
+	r.size = r.size / 2
+	if r.size < r.Min {
+		r.size = r.Min
+	}
+	return true
+}
+
+func (r *BlockRange) Grow() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.size = r.size * 2
+	if r.size > r.Max {
+		r.size = r.Max
+	}
+}
+
+func IsRangeLimitError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, limit := range rangeLimitErrors {
+		if strings.Contains(msg, limit) {
+			return true
+		}
+	}
+	return false
+}
+
+// getEventByAdaptiveRange fetches the events from fromBlock on, covering at
+// most toBlock, and returns the last block it covered. The range is the one of
+// the endpoint serving the contract logs, and is bisected while an endpoint
+// rejects it for being too large.
+func (tracking *TrackingEvent) getEventByAdaptiveRange(fromBlock, toBlock int64) (*database.RangeEvents, int64, error) {
+	for {
+		var blockRange *BlockRange
+		var end int64
+		var logs []types.Log
+		err := tracking.Pool.Call("eth_getLogs", 1, func(endpoint *Endpoint) error {
+			blockRange = endpoint.Range
+			end = fromBlock + blockRange.Size() - 1
+			if end > toBlock {
+				end = toBlock
+			}
+
+			var err error
+			logs, err = endpointLogs(endpoint, tracking.contractQuery(big.NewInt(fromBlock), big.NewInt(end)))
+			return err
+		})
+
+		var events *database.RangeEvents
+		if err == nil {
+			events, err = tracking.rangeEvents(big.NewInt(fromBlock), big.NewInt(end), logs)
+		}
+		if errors.Is(err, errRangeShrunk) {
+			continue
+		}
+		if err != nil {
+			return nil, end, err
+		}
+
+		if len(events.Request)+len(events.Response)+len(events.VrfRequest)+len(events.VrfFulfillment) < growLogs {
+			blockRange.Grow()
+		}
+		return events, end, nil
+	}
+}
+
+// endpointLogs runs a FilterLogs call on endpoint. When the endpoint rejects
+// the range for being too large, its range is shrunk and the error also wraps
+// errRangeShrunk, so that the range is fetched again in smaller parts.
+func endpointLogs(endpoint *Endpoint, query ethereum.FilterQuery) ([]types.Log, error) {
+	logs, err := endpoint.Client.FilterLogs(context.Background(), query)
+	if IsRangeLimitError(err) && endpoint.Range != nil && endpoint.Range.Shrink() {
+		return nil, fmt.Errorf("%w: %w", errRangeShrunk, err)
+	}
+
+	return logs, err
+}
+
+// getEventByRange fetches every event between fromBlock and toBlock, split in
+// as many adaptive ranges as needed.
+func (tracking *TrackingEvent) getEventByRange(fromBlock, toBlock int64) (*database.RangeEvents, error) {
+	events := &database.RangeEvents{}
+	for fromBlock <= toBlock {
+		rangeEvents, end, err := tracking.getEventByAdaptiveRange(fromBlock, toBlock)
+		if err != nil {
+			return nil, err
+		}
+
+		events.Request = append(events.Request, rangeEvents.Request...)
+		events.Response = append(events.Response, rangeEvents.Response...)
+		events.VrfRequest = append(events.VrfRequest, rangeEvents.VrfRequest...)
+		events.VrfFulfillment = append(events.VrfFulfillment, rangeEvents.VrfFulfillment...)
+		events.Failed = append(events.Failed, rangeEvents.Failed...)
+		events.Verification = append(events.Verification, rangeEvents.Verification...)
+		events.Subscription = append(events.Subscription, rangeEvents.Subscription...)
+		events.Blocks = append(events.Blocks, rangeEvents.Blocks...)
+		events.Unresolved = append(events.Unresolved, rangeEvents.Unresolved...)
+		fromBlock = end + 1
+	}
+
+	return events, nil
+}
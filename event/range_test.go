@@ -0,0 +1,155 @@
+package event
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/rpc"
+	"testing"
+)
+
+func TestBlockRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		min   int64
+		max   int64
+		steps string
+		sizes []int64
+	}{
+		{
+			name: "starts at max",
+			min:  10,
+			max:  5000,
+		},
+		{
+			name:  "shrinks by half down to min",
+			min:   10,
+			max:   80,
+			steps: "ssss",
+			sizes: []int64{40, 20, 10, 10},
+		},
+		{
+			name:  "shrinks to min when half is below it",
+			min:   30,
+			max:   50,
+			steps: "s",
+			sizes: []int64{30},
+		},
+		{
+			name:  "grows back up to max",
+			min:   10,
+			max:   100,
+			steps: "sssggg",
+			sizes: []int64{50, 25, 12, 24, 48, 96},
+		},
+		{
+			name:  "never grows past max",
+			min:   10,
+			max:   100,
+			steps: "sgg",
+			sizes: []int64{50, 100, 100},
+		},
+		{
+			name:  "max below min",
+			min:   10,
+			max:   5,
+			steps: "sg",
+			sizes: []int64{10, 10},
+		},
+		{
+			name:  "min not set",
+			min:   0,
+			max:   2,
+			steps: "ss",
+			sizes: []int64{1, 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewBlockRange(test.min, test.max)
+			if r.Size() != r.Max {
+				t.Fatalf("starts at %d, want %d", r.Size(), r.Max)
+			}
+
+			for i, step := range test.steps {
+				before := r.Size()
+				if step == 's' {
+					shrunk := r.Shrink()
+					if shrunk != (before > r.Min) {
+						t.Errorf("step %d: shrink from %d returned %t", i, before, shrunk)
+					}
+				} else {
+					r.Grow()
+				}
+
+				if r.Size() != test.sizes[i] {
+					t.Errorf("step %d: size is %d, want %d", i, r.Size(), test.sizes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIsRangeLimitError(t *testing.T) {
+	tests := []struct {
+		err   error
+		limit bool
+	}{
+		{nil, false},
+		{errors.New("query returned more than 10000 results"), true},
+		{errors.New("Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"), true},
+		{errors.New("exceed maximum block range: 5000"), true},
+		{errors.New("block range is too wide"), true},
+		{errors.New("eth_getLogs block range too large, range: 10001, max: 10000"), true},
+		{errors.New("too many results, max 10000"), true},
+		{errors.New("query timeout exceeded"), true},
+		{&rpcError{code: -32005, message: "range limit exceeded"}, true},
+		{errors.New("block range extends beyond current head block"), false},
+		{errors.New("invalid block range params"), false},
+		{errors.New("rate limit exceeded"), false},
+		{&rpcError{code: -32005, message: "daily request count exceeded, request rate limited"}, false},
+		{errors.New("header not found"), false},
+	}
+
+	for _, test := range tests {
+		if IsRangeLimitError(test.err) != test.limit {
+			t.Errorf("IsRangeLimitError(%v) = %t, want %t", test.err, !test.limit, test.limit)
+		}
+	}
+}
+
+// rpcError is an error answered by a node, as decoded by the rpc package.
+type rpcError struct {
+	code    int
+	message string
+}
+
+func (e *rpcError) Error() string {
+	return e.message
+}
+
+func (e *rpcError) ErrorCode() int {
+	return e.code
+}
+
+var _ rpc.Error = (*rpcError)(nil)
+
+// TestAdaptiveRangePerEndpoint rejects the range on one endpoint only, which
+// shrinks the range of that endpoint and leaves the other one's alone.
+func TestAdaptiveRangePerEndpoint(t *testing.T) {
+	limited := newTestChain(100)
+	limited.limit = 4
+	a := testEndpoint(t, "a", limited, 1, 8)
+	b := testEndpoint(t, "b", newTestChain(100), 1, 8)
+	tracking := testTracking(t, nil, a, b)
+
+	_, end, err := tracking.getEventByAdaptiveRange(1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if end != 8 {
+		t.Errorf("covered up to %d, want 8 from endpoint b", end)
+	}
+	if a.Range.Size() != 4 || b.Range.Size() != 8 {
+		t.Errorf("got range sizes %d and %d, want 4 and 8", a.Range.Size(), b.Range.Size())
+	}
+}
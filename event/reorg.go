@@ -0,0 +1,54 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"errors"
+	"fmt"
+	"github.com/uptrace/bun"
+	"math/big"
+)
+
+// maxReorgDepth is the number of stored blocks walked back while looking for
+// the common ancestor of the stored and the canonical chain.
+const maxReorgDepth = 128
+
+var ErrReorg = errors.New("event: chain reorganization while reading logs")
+
+// CheckReorg compares the stored blocks up to number with the canonical chain
+// and returns the last stored block both agree on, and whether stored blocks
+// above it were reorganized away. A stored block only counts as canonical when
+// its parent hash also matches the stored parent, or the canonical one when
+// the parent was not stored.
+func (tracking *TrackingEvent) CheckReorg(db bun.IDB, number int64) (int64, bool, error) {
+	blocks, err := database.GetBlocksFromDb(db, tracking.ChainId, tracking.Address.String(), number, maxReorgDepth)
+	if err != nil {
+		return 0, false, err
+	}
+
+	for i, block := range blocks {
+		header, err := tracking.GetBlock(big.NewInt(block.Number))
+		if err != nil {
+			return 0, false, err
+		}
+
+		if header.Hash != block.Hash {
+			continue
+		}
+
+		parentHash := header.ParentHash
+		if i+1 < len(blocks) && blocks[i+1].Number == block.Number-1 {
+			parentHash = blocks[i+1].Hash
+		}
+		if block.ParentHash != parentHash {
+			continue
+		}
+
+		return block.Number, i > 0, nil
+	}
+
+	if len(blocks) == 0 {
+		return number, false, nil
+	}
+
+	return 0, false, fmt.Errorf("event: no common ancestor in the last %d stored blocks", maxReorgDepth)
+}
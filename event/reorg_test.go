@@ -0,0 +1,105 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"math/big"
+	"testing"
+)
+
+func TestCheckReorg(t *testing.T) {
+	db := testDatabase(t)
+
+	tests := []struct {
+		name     string
+		stored   []int64
+		fork     int64
+		tamper   func(blocks map[int64]*database.Block)
+		ancestor int64
+		reorg    bool
+	}{
+		{
+			name:     "nothing stored",
+			ancestor: 12,
+		},
+		{
+			name:     "newest stored block below number",
+			stored:   []int64{6, 8, 10},
+			ancestor: 10,
+		},
+		{
+			name:     "fork above the stored blocks",
+			stored:   []int64{6, 8, 10},
+			fork:     11,
+			ancestor: 10,
+		},
+		{
+			name:     "fork below the stored blocks",
+			stored:   []int64{6, 8, 10},
+			fork:     8,
+			ancestor: 6,
+			reorg:    true,
+		},
+		{
+			name:   "stored parent of another chain",
+			stored: []int64{8, 9, 10},
+			tamper: func(blocks map[int64]*database.Block) {
+				blocks[9].Hash = "0x01"
+			},
+			ancestor: 8,
+			reorg:    true,
+		},
+		{
+			name:   "only the child stored",
+			stored: []int64{6, 10},
+			tamper: func(blocks map[int64]*database.Block) {
+				blocks[10].ParentHash = "0x01"
+			},
+			ancestor: 6,
+			reorg:    true,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chain := newTestChain(12)
+			tracking := testTracking(t, db, testEndpoint(t, "test", chain, 2, 2))
+			tracking.ChainId = int64(i + 1)
+
+			blocks := make(map[int64]*database.Block)
+			var rows []database.Block
+			for _, number := range test.stored {
+				block, err := tracking.GetBlock(big.NewInt(number))
+				if err != nil {
+					t.Fatal(err)
+				}
+				block.ChainId = tracking.ChainId
+				block.Contract = tracking.Address.String()
+				blocks[number] = block
+			}
+			if test.tamper != nil {
+				test.tamper(blocks)
+			}
+			for _, number := range test.stored {
+				rows = append(rows, *blocks[number])
+			}
+			if len(rows) > 0 {
+				err := database.InsertBlockToDb(db, rows)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if test.fork > 0 {
+				chain.fork(test.fork, 12, "b")
+			}
+
+			ancestor, reorg, err := tracking.CheckReorg(db, 12)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ancestor != test.ancestor || reorg != test.reorg {
+				t.Errorf("got ancestor %d and reorg %t, want %d and %t", ancestor, reorg, test.ancestor, test.reorg)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"fmt"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+const (
+	retryDelay     = 30 * time.Second
+	retryBaseDelay = time.Minute
+	retryMaxDelay  = 6 * time.Hour
+	retryBatchSize = 100
+)
+
+// RetryBlockError re-indexes the block ranges stored in error_block. A range
+// that fails again is rescheduled with an exponential backoff, a range that
+// succeeds is stored and marked as resolved.
+func (tracking *TrackingEvent) RetryBlockError(db *bun.DB) {
+	for {
+		blockErrors, err := database.GetBlockErrorFromDb(db, tracking.ChainId, tracking.Address.String(), retryBatchSize)
+		if err != nil {
+			fmt.Println("get block error from db:", err)
+			time.Sleep(retryDelay)
+			continue
+		}
+
+		for i := range blockErrors {
+			tracking.retryBlockError(db, &blockErrors[i])
+		}
+
+		time.Sleep(retryDelay)
+	}
+}
+
+func (tracking *TrackingEvent) retryBlockError(db *bun.DB, blockErr *database.BlockError) {
+	toBlock := blockErr.ToBlock
+	if toBlock == 0 {
+		// ranges recorded before the end block was stored
+		toBlock = blockErr.Block + blockRange
+	}
+
+	blockErr.Attempts++
+	err := tracking.retryRange(db, blockErr, int64(blockErr.Block), int64(toBlock))
+	if err == nil {
+		fmt.Println("resolved block error:", blockErr.Block, toBlock)
+		return
+	}
+
+	fmt.Println("retry block error:", blockErr.Block, err)
+	blockErr.LastError = err.Error()
+	blockErr.RetryAt = time.Now().Add(retryBackoff(blockErr.Attempts))
+	err = database.UpdateBlockErrorToDb(db, blockErr)
+	if err != nil {
+		fmt.Println("update block error to db:", err)
+	}
+}
+
+// retryRange indexes a failed range the way indexNextRange does: the stored
+// events are finalized, the stored blocks before the range are checked against
+// the canonical chain and the events are stored with their finality. A reorg
+// is not rolled back here, the tracker does it from its own position, the
+// range is retried after it.
+func (tracking *TrackingEvent) retryRange(db *bun.DB, blockErr *database.BlockError, fromBlock, toBlock int64) error {
+	_, finalBlock, err := tracking.finalizeEvents(db)
+	if err != nil {
+		return err
+	}
+
+	_, reorg, err := tracking.CheckReorg(db, fromBlock-1)
+	if err != nil {
+		return err
+	}
+	if reorg {
+		return ErrReorg
+	}
+
+	events, err := tracking.getEventByRange(fromBlock, toBlock)
+	if err != nil {
+		return err
+	}
+
+	err = tracking.resolveFailed(db, events)
+	if err != nil {
+		return err
+	}
+
+	setFinal(events, finalBlock)
+	return database.ResolveBlockErrorToDb(db, blockErr, events)
+}
+
+func retryBackoff(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay = delay * 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+
+	return delay
+}
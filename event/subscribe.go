@@ -0,0 +1,127 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/uptrace/bun"
+	"math/big"
+	"time"
+)
+
+// resubscribeDelay is how long the tracker falls back to polling after the
+// websocket subscription dropped before it subscribes again.
+const resubscribeDelay = 5 * time.Minute
+
+// SubscribeEventFromBlockNumber indexes the contract events like
+// GetEventFromBlockNumber, but is woken up by an eth_subscribe log
+// subscription on the websocket RPC instead of polling the chain head. Every
+// received log triggers the indexing of the blocks between the checkpoint and
+// the log, so the gap left before the subscription started or while it was
+// down is always filled first. When the subscription fails the tracker polls
+// until it is able to subscribe again.
+func (tracking *TrackingEvent) SubscribeEventFromBlockNumber(db *bun.DB, wsRpc string, number *big.Int) error {
+	fromBlock, err := tracking.startBlock(db, number)
+	if err != nil {
+		return err
+	}
+
+	for {
+		fromBlock, err = tracking.subscribe(db, wsRpc, fromBlock)
+		fmt.Println("subscription:", err)
+
+		deadline := time.Now().Add(resubscribeDelay)
+		for time.Now().Before(deadline) {
+			next, err := tracking.indexNextRange(db, fromBlock)
+			caughtUp := next == fromBlock
+			fromBlock = next
+			if errors.Is(err, ErrReorg) {
+				fmt.Println(fromBlock, err)
+				time.Sleep(reorgDelay)
+				continue
+			}
+			if err != nil {
+				fmt.Println(fromBlock, err)
+				time.Sleep(headDelay)
+				continue
+			}
+
+			if caughtUp {
+				time.Sleep(headDelay)
+			}
+		}
+	}
+}
+
+// subscribe runs until the subscription fails and returns the block to
+// continue from.
+func (tracking *TrackingEvent) subscribe(db *bun.DB, wsRpc string, fromBlock int64) (int64, error) {
+	// catch up before subscribing, so a long backfill does not overflow the
+	// subscription buffer
+	fromBlock = tracking.syncToHead(db, fromBlock)
+
+	client, err := ethclient.Dial(wsRpc)
+	if err != nil {
+		return fromBlock, err
+	}
+	defer client.Close()
+
+	logs := make(chan types.Log)
+	query := ethereum.FilterQuery{
+		Addresses: []common.Address{
+			tracking.Address,
+		},
+	}
+
+	sub, err := client.SubscribeFilterLogs(context.Background(), query, logs)
+	if err != nil {
+		return fromBlock, err
+	}
+	defer sub.Unsubscribe()
+
+	// the node behind the polling client may lag behind the websocket node,
+	// so the head is still checked regularly while subscribed
+	ticker := time.NewTicker(headDelay)
+	defer ticker.Stop()
+
+	for {
+		fromBlock = tracking.syncToHead(db, fromBlock)
+
+		select {
+		case err := <-sub.Err():
+			return fromBlock, err
+		case vLog := <-logs:
+			if vLog.Removed {
+				fmt.Println("removed log in block:", vLog.BlockNumber)
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncToHead indexes every block from fromBlock up to the chain head and
+// returns the block to continue from.
+func (tracking *TrackingEvent) syncToHead(db *bun.DB, fromBlock int64) int64 {
+	for {
+		next, err := tracking.indexNextRange(db, fromBlock)
+		caughtUp := next == fromBlock
+		fromBlock = next
+		if errors.Is(err, ErrReorg) {
+			fmt.Println(fromBlock, err)
+			time.Sleep(reorgDelay)
+			continue
+		}
+		if err != nil {
+			fmt.Println(fromBlock, err)
+			return fromBlock
+		}
+
+		if caughtUp {
+			return fromBlock
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"testing"
+)
+
+// TestSyncToHeadAfterRollback reorganizes indexed blocks and fails the first
+// range after the rollback, so that the range has to be indexed again from
+// the rolled back block rather than from the block the sync started at.
+func TestSyncToHeadAfterRollback(t *testing.T) {
+	db := testDatabase(t)
+	reorgDelay = 0
+
+	chain := newTestChain(12)
+	tracking := testTracking(t, db, testEndpoint(t, "test", chain, 2, 2))
+
+	next := tracking.syncToHead(db, 1)
+	if next != 13 {
+		t.Fatalf("synced to %d, want 13", next)
+	}
+
+	chain.fork(8, 14, "b")
+	calls := 0
+	chain.logs = func(from, to uint64) []types.Log {
+		if from > 8 || to < 8 {
+			return nil
+		}
+
+		// the first answer still comes from a node on the old chain
+		calls++
+		hash := chain.hash(8)
+		if calls == 1 {
+			hash = common.Hash{1}
+		}
+		return []types.Log{testRequestLog(t, 8, hash, 1)}
+	}
+
+	next = tracking.syncToHead(db, next)
+	if next != 15 {
+		t.Fatalf("synced to %d, want 15", next)
+	}
+
+	var requests []database.RequestRandom
+	err := db.NewSelect().Model(&requests).Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want the request of block 8", len(requests))
+	}
+	if requests[0].BlockNumber != 8 || requests[0].BlockHash != chain.hash(8).String() {
+		t.Errorf("request got block %d %s", requests[0].BlockNumber, requests[0].BlockHash)
+	}
+
+	checkpoint, err := database.GetCheckpointFromDb(db, tracking.ChainId, tracking.Address.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Block != 14 {
+		t.Errorf("checkpoint is %d, want 14", checkpoint.Block)
+	}
+}
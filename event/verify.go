@@ -0,0 +1,159 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/uptrace/bun"
+	"math/big"
+	"time"
+)
+
+const (
+	verifyBatchSize = 100
+	// unverifiedKey is the reason of a proof that is correct but was made
+	// with a key that cannot be checked, because no proving key is
+	// configured. Such a proof is not valid.
+	unverifiedKey = "unverified-key"
+)
+
+// VerifyFulfillments checks the VRF proof of every stored fulfillment of the
+// contract that has no verdict yet, so that fulfillments indexed without
+// VerifyProofs can be verified afterwards.
+func (tracking *TrackingEvent) VerifyFulfillments(db *bun.DB) error {
+	afterId := 0
+	for {
+		fulfillments, err := database.GetUnverifiedFulfillmentsFromDb(db, tracking.ChainId, tracking.Address.String(), afterId, verifyBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(fulfillments) == 0 {
+			return nil
+		}
+
+		var verifications []database.VrfVerification
+		for _, fulfillment := range fulfillments {
+			afterId = fulfillment.Id
+			verification, err := tracking.verifyStoredFulfillment(fulfillment)
+			if err != nil {
+				fmt.Println("verify fulfillment:", fulfillment.RequestId, err)
+				continue
+			}
+
+			verifications = append(verifications, *verification)
+		}
+
+		err = database.UpsertVrfVerificationToDb(db, verifications)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (tracking *TrackingEvent) verifyStoredFulfillment(fulfillment database.VrfFulfillment) (*database.VrfVerification, error) {
+	requestId, ok := new(big.Int).SetString(fulfillment.RequestId, 10)
+	if !ok {
+		return nil, fmt.Errorf("event: invalid request id %q", fulfillment.RequestId)
+	}
+
+	outputSeed, ok := new(big.Int).SetString(fulfillment.OutputSeed, 10)
+	if !ok {
+		return nil, fmt.Errorf("event: invalid output seed %q", fulfillment.OutputSeed)
+	}
+
+	txHash := common.HexToHash(fulfillment.TxHash)
+	proof, commitment, err := tracking.fulfillmentInput(txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return tracking.verifyFulfillment(txHash, fulfillment.BlockNumber, requestId, outputSeed, proof, commitment)
+}
+
+// verifyFulfillment checks that the proof sent in a fulfillment was made with
+// a registered proving key for the request, on the hash of the block of the
+// request, and that it proves the output seed the coordinator emitted. Errors
+// are only returned when the block hash cannot be fetched, a rejected proof is
+// a verdict.
+func (tracking *TrackingEvent) verifyFulfillment(txHash common.Hash, blockNumber int64, requestId, outputSeed *big.Int, proof *Proof, commitment *RequestCommitment) (*database.VrfVerification, error) {
+	verification := &database.VrfVerification{
+		ChainId:     tracking.ChainId,
+		Contract:    tracking.Address.String(),
+		RequestId:   requestId.String(),
+		TxHash:      txHash.String(),
+		BlockNumber: blockNumber,
+		VerifiedAt:  time.Now(),
+	}
+
+	if proof == nil || commitment == nil {
+		verification.Reason = "transaction does not call fulfillRandomWords"
+		return verification, nil
+	}
+
+	keyHash := KeyHash(proof.Pk)
+	verification.KeyHash = keyHash.String()
+	if !tracking.provingKey(keyHash) {
+		verification.Reason = "proving key is not registered"
+		return verification, nil
+	}
+
+	if RequestId(keyHash, proof.Seed).Cmp(requestId) != 0 {
+		verification.Reason = "proof seed does not belong to the request"
+		return verification, nil
+	}
+
+	blockHash, err := tracking.blockHash(int64(commitment.BlockNum))
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := VerifyProof(proof, ProofSeed(proof.Seed, blockHash))
+	if err != nil {
+		verification.Reason = err.Error()
+		return verification, nil
+	}
+
+	if output.Cmp(outputSeed) != 0 {
+		verification.Reason = "proof does not match the output seed"
+		return verification, nil
+	}
+
+	if len(tracking.ProvingKeys) == 0 {
+		verification.Reason = unverifiedKey
+		return verification, nil
+	}
+
+	verification.Valid = true
+	return verification, nil
+}
+
+// provingKey tells whether keyHash is one of the configured proving keys.
+// Every key passes when none is configured, the verdict is then
+// unverifiedKey.
+func (tracking *TrackingEvent) provingKey(keyHash common.Hash) bool {
+	if len(tracking.ProvingKeys) == 0 {
+		return true
+	}
+
+	for _, key := range tracking.ProvingKeys {
+		if key == keyHash {
+			return true
+		}
+	}
+	return false
+}
+
+func (tracking *TrackingEvent) blockHash(number int64) (common.Hash, error) {
+	block, ok := tracking.Cache.Get(number)
+	if ok {
+		return common.HexToHash(block.Hash), nil
+	}
+
+	fetched, err := tracking.GetBlock(big.NewInt(number))
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	tracking.Cache.Add(*fetched)
+	return common.HexToHash(fetched.Hash), nil
+}
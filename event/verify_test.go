@@ -0,0 +1,101 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+)
+
+func TestVerifyFulfillment(t *testing.T) {
+	sk, k := testKey()
+	preSeed := big.NewInt(123456789)
+	blockNumber := int64(25000000)
+	blockHash := common.HexToHash("0x5c3f1e8d9a0b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d")
+
+	proof := testProof(sk, k, ProofSeed(preSeed, blockHash))
+	// the proof is sent with the preSeed, the coordinator mixes in the block
+	// hash itself
+	proof.Seed = preSeed
+	keyHash := KeyHash(proof.Pk)
+	requestId := RequestId(keyHash, preSeed)
+	outputSeed := new(big.Int).SetBytes(crypto.Keccak256(word(randomOutputPrefix), word(proof.Gamma[0]), word(proof.Gamma[1])))
+	commitment := &RequestCommitment{BlockNum: uint64(blockNumber), NumWords: 1}
+
+	tests := []struct {
+		name        string
+		provingKeys []common.Hash
+		requestId   *big.Int
+		outputSeed  *big.Int
+		proof       *Proof
+		valid       bool
+		reason      string
+	}{
+		{
+			name:        "valid",
+			provingKeys: []common.Hash{keyHash},
+			requestId:   requestId,
+			outputSeed:  outputSeed,
+			proof:       proof,
+			valid:       true,
+		},
+		{
+			name:       "no proving key configured",
+			requestId:  requestId,
+			outputSeed: outputSeed,
+			proof:      proof,
+			reason:     unverifiedKey,
+		},
+		{
+			name:        "other proving key",
+			provingKeys: []common.Hash{{1}},
+			requestId:   requestId,
+			outputSeed:  outputSeed,
+			proof:       proof,
+			reason:      "proving key is not registered",
+		},
+		{
+			name:        "other request",
+			provingKeys: []common.Hash{keyHash},
+			requestId:   new(big.Int).Add(requestId, big.NewInt(1)),
+			outputSeed:  outputSeed,
+			proof:       proof,
+			reason:      "proof seed does not belong to the request",
+		},
+		{
+			name:        "other output seed",
+			provingKeys: []common.Hash{keyHash},
+			requestId:   requestId,
+			outputSeed:  new(big.Int).Add(outputSeed, big.NewInt(1)),
+			proof:       proof,
+			reason:      "proof does not match the output seed",
+		},
+		{
+			name:        "not a fulfillRandomWords call",
+			provingKeys: []common.Hash{keyHash},
+			requestId:   requestId,
+			outputSeed:  outputSeed,
+			reason:      "transaction does not call fulfillRandomWords",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracking := &TrackingEvent{
+				ChainId:     56,
+				ProvingKeys: test.provingKeys,
+				Cache:       NewBlockCache(0, nil),
+			}
+			tracking.Cache.Add(database.Block{Number: blockNumber, Hash: blockHash.String()})
+
+			verification, err := tracking.verifyFulfillment(common.Hash{}, blockNumber, test.requestId, test.outputSeed, test.proof, commitment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verification.Valid != test.valid || verification.Reason != test.reason {
+				t.Errorf("got valid %t and reason %q, want %t and %q", verification.Valid, verification.Reason, test.valid, test.reason)
+			}
+		})
+	}
+}
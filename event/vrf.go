@@ -0,0 +1,478 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/uptrace/bun"
+	"math/big"
+)
+
+//go:embed abi/coordinator.json
+var coordinatorAbi []byte
+
+type RandomWordsRequestedEvent struct {
+	KeyHash                     [32]byte
+	RequestId                   *big.Int
+	PreSeed                     *big.Int
+	SubId                       uint64
+	MinimumRequestConfirmations uint16
+	CallbackGasLimit            uint32
+	NumWords                    uint32
+	Sender                      common.Address
+}
+
+type RandomWordsFulfilledEvent struct {
+	RequestId  *big.Int
+	OutputSeed *big.Int
+	Payment    *big.Int
+	Success    bool
+}
+
+type SubscriptionFundedEvent struct {
+	SubId      uint64
+	OldBalance *big.Int
+	NewBalance *big.Int
+}
+
+// SubscriptionConsumerEvent is a SubscriptionConsumerAdded or a
+// SubscriptionConsumerRemoved event.
+type SubscriptionConsumerEvent struct {
+	SubId    uint64
+	Consumer common.Address
+}
+
+type SubscriptionCanceledEvent struct {
+	SubId  uint64
+	To     common.Address
+	Amount *big.Int
+}
+
+// RequestCommitment is the request the coordinator is asked to fulfill, as
+// sent in the fulfillRandomWords transaction.
+type RequestCommitment struct {
+	BlockNum         uint64
+	SubId            uint64
+	CallbackGasLimit uint32
+	NumWords         uint32
+	Sender           common.Address
+}
+
+// LoadCoordinatorAbi returns the embedded ABI of the VRF v2 coordinator.
+func LoadCoordinatorAbi() (*abi.ABI, error) {
+	contractAbi, err := abi.JSON(bytes.NewReader(coordinatorAbi))
+	if err != nil {
+		return nil, err
+	}
+
+	return &contractAbi, nil
+}
+
+// filterVrfLogs returns the coordinator logs between from and to that belong
+// to the contract: the requests it sent, the fulfillments of its requests and
+// the changes of its subscription. A fulfillment belongs to the contract when
+// its request was sent in the range or answered by a ResponseCreated event of
+// the range. A failed fulfillment has no ResponseCreated event, so the failed
+// fulfillments of other requests are returned apart, for resolveFailed to look
+// their request up among the stored ones.
+func (tracking *TrackingEvent) filterVrfLogs(from, to *big.Int, contractLogs []types.Log) ([]types.Log, []types.Log, error) {
+	if tracking.Coordinator == (common.Address{}) {
+		return nil, nil, nil
+	}
+
+	requested := tracking.CoordinatorAbi.Events["RandomWordsRequested"].ID
+	fulfilled := tracking.CoordinatorAbi.Events["RandomWordsFulfilled"].ID
+
+	requestLogs, err := tracking.filterLogs(ethereum.FilterQuery{
+		FromBlock: from,
+		ToBlock:   to,
+		Addresses: []common.Address{tracking.Coordinator},
+		Topics: [][]common.Hash{
+			{requested},
+			nil,
+			nil,
+			{common.BytesToHash(tracking.Address.Bytes())},
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var logs []types.Log
+	requestIds := make(map[string]bool)
+	for _, vLog := range requestLogs {
+		if vLog.Removed {
+			continue
+		}
+
+		var request RandomWordsRequestedEvent
+		err = UnpackLog(tracking.CoordinatorAbi, &request, "RandomWordsRequested", vLog)
+		if err != nil {
+			return nil, nil, err
+		}
+		requestIds[request.RequestId.String()] = true
+		logs = append(logs, vLog)
+	}
+
+	for _, vLog := range contractLogs {
+		// the request id is the second indexed argument of ResponseCreated
+		if len(vLog.Topics) > 2 && tracking.Abi.Events["ResponseCreated"].ID == vLog.Topics[0] {
+			requestIds[vLog.Topics[2].Big().String()] = true
+		}
+	}
+
+	fulfillLogs, err := tracking.filterLogs(ethereum.FilterQuery{
+		FromBlock: from,
+		ToBlock:   to,
+		Addresses: []common.Address{tracking.Coordinator},
+		Topics:    [][]common.Hash{{fulfilled}},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var failedLogs []types.Log
+	for _, vLog := range fulfillLogs {
+		if vLog.Removed || len(vLog.Topics) < 2 {
+			continue
+		}
+
+		requestId := vLog.Topics[1].Big().String()
+		if requestIds[requestId] {
+			logs = append(logs, vLog)
+			continue
+		}
+
+		var fulfillment RandomWordsFulfilledEvent
+		err = UnpackLog(tracking.CoordinatorAbi, &fulfillment, "RandomWordsFulfilled", vLog)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !fulfillment.Success {
+			failedLogs = append(failedLogs, vLog)
+		}
+	}
+
+	if tracking.SubscriptionId != 0 {
+		var subscriptionEvents []common.Hash
+		for _, name := range []string{"SubscriptionFunded", "SubscriptionConsumerAdded", "SubscriptionConsumerRemoved", "SubscriptionCanceled"} {
+			subscriptionEvents = append(subscriptionEvents, tracking.CoordinatorAbi.Events[name].ID)
+		}
+
+		subscriptionLogs, err := tracking.filterLogs(ethereum.FilterQuery{
+			FromBlock: from,
+			ToBlock:   to,
+			Addresses: []common.Address{tracking.Coordinator},
+			Topics: [][]common.Hash{
+				subscriptionEvents,
+				{common.BigToHash(new(big.Int).SetUint64(tracking.SubscriptionId))},
+			},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		logs = append(logs, subscriptionLogs...)
+	}
+
+	return logs, failedLogs, nil
+}
+
+// resolveFailed decodes the unresolved failed fulfillments of events whose
+// request is stored for the contract, and drops the others. It runs right
+// before the events are stored, range after range, so that the requests of
+// the earlier ranges are stored already.
+func (tracking *TrackingEvent) resolveFailed(db bun.IDB, events *database.RangeEvents) error {
+	if len(events.Unresolved) == 0 {
+		return nil
+	}
+
+	var requestIds []string
+	for _, fulfillment := range events.Unresolved {
+		requestIds = append(requestIds, fulfillment.RequestId)
+	}
+
+	stored, err := database.GetVrfRequestIdsFromDb(db, tracking.ChainId, tracking.Address.String(), requestIds)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool)
+	for _, requestId := range stored {
+		known[requestId] = true
+	}
+
+	var resolved []database.VrfFulfillment
+	var numbers []int64
+	for _, fulfillment := range events.Unresolved {
+		if known[fulfillment.RequestId] {
+			resolved = append(resolved, fulfillment)
+			numbers = append(numbers, fulfillment.BlockNumber)
+		}
+	}
+	events.Unresolved = nil
+	if len(resolved) == 0 {
+		return nil
+	}
+
+	headers, err := tracking.GetBlocks(numbers)
+	if err != nil {
+		return err
+	}
+
+	blocks := make(map[int64]bool)
+	for _, block := range events.Blocks {
+		blocks[block.Number] = true
+	}
+
+	for _, fulfillment := range resolved {
+		block, err := tracking.canonicalBlock(headers[fulfillment.BlockNumber], fulfillment.BlockHash)
+		if err != nil {
+			return err
+		}
+		headers[block.Number] = *block
+		block.ChainId = tracking.ChainId
+		block.Contract = tracking.Address.String()
+
+		vLog, err := tracking.fulfillmentLog(fulfillment)
+		if err != nil {
+			return err
+		}
+
+		err = tracking.decodeVrfLog(events, *vLog, block)
+		if err != nil {
+			return err
+		}
+
+		if !blocks[block.Number] {
+			blocks[block.Number] = true
+			events.Blocks = append(events.Blocks, *block)
+		}
+	}
+
+	return nil
+}
+
+// fulfillmentLog fetches the RandomWordsFulfilled log of a fulfillment again
+// from the receipt of its transaction.
+func (tracking *TrackingEvent) fulfillmentLog(fulfillment database.VrfFulfillment) (*types.Log, error) {
+	var receipt *types.Receipt
+	err := tracking.Pool.Call("eth_getTransactionReceipt", 1, func(endpoint *Endpoint) error {
+		var err error
+		receipt, err = endpoint.Client.TransactionReceipt(context.Background(), common.HexToHash(fulfillment.TxHash))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if receipt.BlockHash.String() != fulfillment.BlockHash {
+		return nil, ErrReorg
+	}
+
+	for _, vLog := range receipt.Logs {
+		if int(vLog.Index) == fulfillment.Index {
+			return vLog, nil
+		}
+	}
+
+	return nil, fmt.Errorf("event: log %d not found in %s", fulfillment.Index, fulfillment.TxHash)
+}
+
+func (tracking *TrackingEvent) filterLogs(query ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := tracking.Pool.Call("eth_getLogs", 1, func(endpoint *Endpoint) error {
+		var err error
+		logs, err = endpointLogs(endpoint, query)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// fulfillmentInput decodes the proof and the request commitment sent to the
+// coordinator in a fulfillRandomWords transaction. Both are nil when the
+// transaction did not call fulfillRandomWords directly.
+func (tracking *TrackingEvent) fulfillmentInput(txHash common.Hash) (*Proof, *RequestCommitment, error) {
+	var tx *types.Transaction
+	err := tracking.Pool.Call("eth_getTransactionByHash", 1, func(endpoint *Endpoint) error {
+		var err error
+		tx, _, err = endpoint.Client.TransactionByHash(context.Background(), txHash)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	method := tracking.CoordinatorAbi.Methods["fulfillRandomWords"]
+	input := tx.Data()
+	if len(input) < 4 || !bytes.Equal(input[:4], method.ID) {
+		return nil, nil, nil
+	}
+
+	args, err := method.Inputs.Unpack(input[4:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("event: decode fulfillRandomWords in %s: %w", txHash, err)
+	}
+
+	proof := *abi.ConvertType(args[0], new(Proof)).(*Proof)
+	commitment := *abi.ConvertType(args[1], new(RequestCommitment)).(*RequestCommitment)
+	return &proof, &commitment, nil
+}
+
+// decodeVrfLog appends the coordinator event of vLog to events.
+func (tracking *TrackingEvent) decodeVrfLog(events *database.RangeEvents, vLog types.Log, block *database.Block) error {
+	event, err := tracking.CoordinatorAbi.EventByID(vLog.Topics[0])
+	if err != nil {
+		return nil
+	}
+
+	switch event.Name {
+	case "RandomWordsRequested":
+		var request RandomWordsRequestedEvent
+		err = UnpackLog(tracking.CoordinatorAbi, &request, event.Name, vLog)
+		if err != nil {
+			return err
+		}
+
+		events.VrfRequest = append(events.VrfRequest, database.VrfRequest{
+			ChainId:                     tracking.ChainId,
+			Contract:                    tracking.Address.String(),
+			Coordinator:                 tracking.Coordinator.String(),
+			RequestId:                   request.RequestId.String(),
+			KeyHash:                     common.Hash(request.KeyHash).String(),
+			PreSeed:                     request.PreSeed.String(),
+			SubId:                       request.SubId,
+			MinimumRequestConfirmations: int(request.MinimumRequestConfirmations),
+			CallbackGasLimit:            int64(request.CallbackGasLimit),
+			NumWords:                    int64(request.NumWords),
+			Sender:                      request.Sender.String(),
+			TxHash:                      vLog.TxHash.String(),
+			Index:                       int(vLog.Index),
+			BlockNumber:                 block.Number,
+			BlockHash:                   block.Hash,
+			Time:                        block.Time,
+		})
+	case "RandomWordsFulfilled":
+		var fulfillment RandomWordsFulfilledEvent
+		err = UnpackLog(tracking.CoordinatorAbi, &fulfillment, event.Name, vLog)
+		if err != nil {
+			return err
+		}
+
+		var proof *Proof
+		var commitment *RequestCommitment
+		if !fulfillment.Success || tracking.VerifyProofs {
+			proof, commitment, err = tracking.fulfillmentInput(vLog.TxHash)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !fulfillment.Success {
+			failed, err := tracking.failedRandom(vLog, fulfillment, commitment, block)
+			if err != nil {
+				return err
+			}
+			events.Failed = append(events.Failed, *failed)
+		}
+
+		if tracking.VerifyProofs {
+			verification, err := tracking.verifyFulfillment(vLog.TxHash, block.Number, fulfillment.RequestId, fulfillment.OutputSeed, proof, commitment)
+			if err != nil {
+				return err
+			}
+			events.Verification = append(events.Verification, *verification)
+		}
+
+		if tracking.SubscriptionId != 0 {
+			events.Subscription = append(events.Subscription, tracking.subscriptionEvent(database.SubscriptionPayment, vLog, block, func(event *database.SubscriptionEvent) {
+				event.Amount = fulfillment.Payment.String()
+				event.RequestId = fulfillment.RequestId.String()
+			}))
+		}
+
+		events.VrfFulfillment = append(events.VrfFulfillment, database.VrfFulfillment{
+			ChainId:     tracking.ChainId,
+			Contract:    tracking.Address.String(),
+			Coordinator: tracking.Coordinator.String(),
+			RequestId:   fulfillment.RequestId.String(),
+			OutputSeed:  fulfillment.OutputSeed.String(),
+			Payment:     fulfillment.Payment.String(),
+			Success:     fulfillment.Success,
+			TxHash:      vLog.TxHash.String(),
+			Index:       int(vLog.Index),
+			BlockNumber: block.Number,
+			BlockHash:   block.Hash,
+			Time:        block.Time,
+		})
+	case "SubscriptionFunded":
+		var funded SubscriptionFundedEvent
+		err = UnpackLog(tracking.CoordinatorAbi, &funded, event.Name, vLog)
+		if err != nil {
+			return err
+		}
+
+		events.Subscription = append(events.Subscription, tracking.subscriptionEvent(database.SubscriptionFunded, vLog, block, func(event *database.SubscriptionEvent) {
+			event.Amount = new(big.Int).Sub(funded.NewBalance, funded.OldBalance).String()
+			event.FundedBalance = funded.NewBalance.String()
+		}))
+	case "SubscriptionConsumerAdded", "SubscriptionConsumerRemoved":
+		var consumer SubscriptionConsumerEvent
+		err = UnpackLog(tracking.CoordinatorAbi, &consumer, event.Name, vLog)
+		if err != nil {
+			return err
+		}
+
+		kind := database.SubscriptionConsumerAdded
+		if event.Name == "SubscriptionConsumerRemoved" {
+			kind = database.SubscriptionConsumerRemoved
+		}
+		events.Subscription = append(events.Subscription, tracking.subscriptionEvent(kind, vLog, block, func(event *database.SubscriptionEvent) {
+			event.Consumer = consumer.Consumer.String()
+		}))
+	case "SubscriptionCanceled":
+		var canceled SubscriptionCanceledEvent
+		err = UnpackLog(tracking.CoordinatorAbi, &canceled, event.Name, vLog)
+		if err != nil {
+			return err
+		}
+
+		events.Subscription = append(events.Subscription, tracking.subscriptionEvent(database.SubscriptionCanceled, vLog, block, func(event *database.SubscriptionEvent) {
+			event.Amount = canceled.Amount.String()
+			event.Consumer = canceled.To.String()
+		}))
+	}
+
+	return nil
+}
+
+// subscriptionEvent returns a ledger entry of the subscription of the
+// contract for vLog, completed by set.
+func (tracking *TrackingEvent) subscriptionEvent(kind string, vLog types.Log, block *database.Block, set func(event *database.SubscriptionEvent)) database.SubscriptionEvent {
+	event := database.SubscriptionEvent{
+		ChainId:       tracking.ChainId,
+		Contract:      tracking.Address.String(),
+		SubId:         tracking.SubscriptionId,
+		Kind:          kind,
+		Amount:        "0",
+		FundedBalance: "0",
+		TxHash:        vLog.TxHash.String(),
+		Index:         int(vLog.Index),
+		BlockNumber:   block.Number,
+		BlockHash:     block.Hash,
+		Time:          block.Time,
+	}
+	set(&event)
+
+	return event
+}
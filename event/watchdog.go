@@ -0,0 +1,171 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/uptrace/bun"
+	"net/http"
+	"time"
+)
+
+const (
+	watchdogDelay     = time.Minute
+	watchdogBatchSize = 1000
+	webhookTimeout    = 10 * time.Second
+)
+
+// WatchdogConfig sets how many blocks a request may stay without a response
+// before an alert is raised, and where the alerts are sent: "log" prints
+// them and "webhook" posts them as JSON to WebhookUrl.
+type WatchdogConfig struct {
+	MaxPendingBlocks int64  `json:"maxPendingBlocks"`
+	Sink             string `json:"sink"`
+	WebhookUrl       string `json:"webhookUrl"`
+}
+
+// Alert is raised once for each request pending for too long.
+type Alert struct {
+	ChainId     int64   `json:"chainId"`
+	Contract    string  `json:"contract"`
+	RequestId   string  `json:"requestId"`
+	User        string  `json:"user"`
+	TxHash      string  `json:"txHash"`
+	BlockNumber int64   `json:"blockNumber"`
+	AgeBlocks   int64   `json:"ageBlocks"`
+	AgeSeconds  float64 `json:"ageSeconds"`
+	Failed      bool    `json:"failed"`
+	Message     string  `json:"message"`
+}
+
+// AlertSink receives the alerts of the watchdog.
+type AlertSink interface {
+	Send(alert Alert) error
+}
+
+type LogSink struct{}
+
+func (sink LogSink) Send(alert Alert) error {
+	fmt.Println("alert:", alert.Message)
+	return nil
+}
+
+type WebhookSink struct {
+	Url    string
+	Client *http.Client
+}
+
+func (sink *WebhookSink) Send(alert Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	resp, err := sink.Client.Post(sink.Url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("event: webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+func NewAlertSink(config WatchdogConfig) (AlertSink, error) {
+	switch config.Sink {
+	case "", "log":
+		return LogSink{}, nil
+	case "webhook":
+		if config.WebhookUrl == "" {
+			return nil, fmt.Errorf("event: webhook sink has no url")
+		}
+		return &WebhookSink{
+			Url:    config.WebhookUrl,
+			Client: &http.Client{Timeout: webhookTimeout},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("event: unknown alert sink %q", config.Sink)
+}
+
+// Watchdog raises an alert through Sink for every request that has stayed
+// without a response for more than MaxPendingBlocks blocks. A request is only
+// alerted once, the time of its alert is stored with it.
+type Watchdog struct {
+	MaxPendingBlocks int64
+	Sink             AlertSink
+}
+
+func NewWatchdog(config WatchdogConfig) (*Watchdog, error) {
+	if config.MaxPendingBlocks <= 0 {
+		return nil, fmt.Errorf("event: watchdog needs maxPendingBlocks")
+	}
+
+	sink, err := NewAlertSink(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watchdog{
+		MaxPendingBlocks: config.MaxPendingBlocks,
+		Sink:             sink,
+	}, nil
+}
+
+// WatchPending checks the pending requests of the contract every
+// watchdogDelay and alerts the ones that are stuck.
+func (tracking *TrackingEvent) WatchPending(db *bun.DB) {
+	if tracking.Watchdog == nil {
+		return
+	}
+
+	for {
+		err := tracking.checkPending(db)
+		if err != nil {
+			fmt.Println("check pending requests:", err)
+		}
+
+		time.Sleep(watchdogDelay)
+	}
+}
+
+func (tracking *TrackingEvent) checkPending(db *bun.DB) error {
+	watchdog := tracking.Watchdog
+	pending, err := database.GetPendingRequestsFromDb(db, tracking.ChainId, tracking.Address.String(), watchdog.MaxPendingBlocks, watchdogBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, request := range pending {
+		message := fmt.Sprintf("request %s on chain %d is pending for %d blocks", request.RequestId, request.ChainId, request.AgeBlocks)
+		if request.Failed {
+			message = message + ", its fulfillment failed"
+		}
+
+		err = watchdog.Sink.Send(Alert{
+			ChainId:     request.ChainId,
+			Contract:    request.Contract,
+			RequestId:   request.RequestId,
+			User:        request.User,
+			TxHash:      request.TxHash,
+			BlockNumber: request.BlockNumber,
+			AgeBlocks:   request.AgeBlocks,
+			AgeSeconds:  request.AgeSeconds,
+			Failed:      request.Failed,
+			Message:     message,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = database.UpdateRequestAlertedToDb(db, &request.RequestRandom)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
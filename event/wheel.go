@@ -0,0 +1,197 @@
+package event
+
+import (
+	"VRFChainlink/database"
+	"errors"
+	"fmt"
+	"github.com/uptrace/bun"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	checkPrizeDelay     = time.Minute
+	checkPrizeBatchSize = 100
+)
+
+var ErrUnknownWheel = errors.New("event: unknown wheel model")
+
+// WheelModel reproduces how a contract turns the random words of a
+// fulfillment into the prize ids of its ResponseCreated event.
+type WheelModel interface {
+	Name() string
+	PrizeIds(words []*big.Int) ([]int, error)
+}
+
+// WheelConfig selects the wheel model of a contract by name. Weights are the
+// odds of each prize id for the weighted model.
+type WheelConfig struct {
+	Model   string  `json:"model"`
+	Weights []int64 `json:"weights"`
+}
+
+var (
+	wheelMu     sync.Mutex
+	wheelModels = map[string]func(config WheelConfig) (WheelModel, error){
+		"weighted": NewWeightedWheel,
+	}
+)
+
+// RegisterWheelModel makes a wheel model available to the contract config
+// under name.
+func RegisterWheelModel(name string, factory func(config WheelConfig) (WheelModel, error)) {
+	wheelMu.Lock()
+	defer wheelMu.Unlock()
+
+	wheelModels[name] = factory
+}
+
+func NewWheelModel(config WheelConfig) (WheelModel, error) {
+	wheelMu.Lock()
+	factory, ok := wheelModels[config.Model]
+	wheelMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownWheel, config.Model)
+	}
+
+	return factory(config)
+}
+
+// WeightedWheel draws one prize per random word: the word modulo the sum of
+// the weights falls in the slot of a prize id, each slot being as wide as the
+// weight of its id.
+type WeightedWheel struct {
+	Weights []int64
+	total   *big.Int
+}
+
+func NewWeightedWheel(config WheelConfig) (WheelModel, error) {
+	total := big.NewInt(0)
+	for _, weight := range config.Weights {
+		if weight < 0 {
+			return nil, errors.New("event: negative wheel weight")
+		}
+		total.Add(total, big.NewInt(weight))
+	}
+
+	if total.Sign() == 0 {
+		return nil, errors.New("event: wheel has no weight")
+	}
+
+	return &WeightedWheel{
+		Weights: config.Weights,
+		total:   total,
+	}, nil
+}
+
+func (wheel *WeightedWheel) Name() string {
+	return "weighted"
+}
+
+func (wheel *WeightedWheel) PrizeIds(words []*big.Int) ([]int, error) {
+	prizeIds := make([]int, len(words))
+	for i, word := range words {
+		// the sum of the weights may not fit in an int64
+		slot := new(big.Int).Mod(word, wheel.total)
+		bound := new(big.Int)
+		for id, weight := range wheel.Weights {
+			bound.Add(bound, big.NewInt(weight))
+			if slot.Cmp(bound) < 0 {
+				prizeIds[i] = id
+				break
+			}
+		}
+	}
+
+	return prizeIds, nil
+}
+
+// CheckPrizes compares, for every successfully fulfilled spin, the prize ids
+// the contract emitted with the ones the wheel model derives from the random
+// words, and logs each mismatch.
+func (tracking *TrackingEvent) CheckPrizes(db *bun.DB) {
+	if tracking.Wheel == nil {
+		return
+	}
+
+	for {
+		err := tracking.checkPrizes(db)
+		if err != nil {
+			fmt.Println("check prizes:", err)
+		}
+
+		time.Sleep(checkPrizeDelay)
+	}
+}
+
+func (tracking *TrackingEvent) checkPrizes(db *bun.DB) error {
+	for {
+		spins, err := database.GetUncheckedSpinsFromDb(db, tracking.ChainId, tracking.Address.String(), checkPrizeBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(spins) == 0 {
+			return nil
+		}
+
+		var checks []database.PrizeCheck
+		for _, spin := range spins {
+			check := tracking.checkPrize(spin)
+			if !check.Match {
+				fmt.Println("prize mismatch:", check.RequestId, check.Emitted, check.Derived, check.Reason)
+			}
+			checks = append(checks, check)
+		}
+
+		err = database.UpsertPrizeCheckToDb(db, checks)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (tracking *TrackingEvent) checkPrize(spin database.FulfilledSpin) database.PrizeCheck {
+	check := database.PrizeCheck{
+		ChainId:     tracking.ChainId,
+		Contract:    tracking.Address.String(),
+		RequestId:   spin.RequestId,
+		Model:       tracking.Wheel.Name(),
+		Emitted:     spin.PrizeIds,
+		BlockNumber: spin.BlockNumber,
+		CheckedAt:   time.Now(),
+	}
+
+	outputSeed, ok := new(big.Int).SetString(spin.OutputSeed, 10)
+	if !ok {
+		check.Reason = fmt.Sprintf("invalid output seed %q", spin.OutputSeed)
+		return check
+	}
+
+	derived, err := tracking.Wheel.PrizeIds(RandomWords(outputSeed, uint32(spin.NumWords)))
+	if err != nil {
+		check.Reason = err.Error()
+		return check
+	}
+
+	check.Derived = make([]database.BigInt, len(derived))
+	for i, prizeId := range derived {
+		check.Derived[i].SetInt64(int64(prizeId))
+	}
+	check.Match = samePrizeIds(check.Derived, spin.PrizeIds)
+	return check
+}
+
+func samePrizeIds(a, b []database.BigInt) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Cmp(&b[i].Int) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
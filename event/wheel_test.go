@@ -0,0 +1,104 @@
+package event
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestWeightedWheel(t *testing.T) {
+	maxWord := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	// the sum of two math.MaxInt64 weights is 2^64 - 2
+	lastOfTwo := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 64), big.NewInt(3))
+
+	tests := []struct {
+		name    string
+		weights []int64
+		word    *big.Int
+		prizeId int
+	}{
+		{
+			name:    "first slot",
+			weights: []int64{1, 0, 2, 3},
+			word:    big.NewInt(0),
+			prizeId: 0,
+		},
+		{
+			name:    "zero weight slot is skipped",
+			weights: []int64{1, 0, 2, 3},
+			word:    big.NewInt(1),
+			prizeId: 2,
+		},
+		{
+			name:    "last word of a slot",
+			weights: []int64{1, 0, 2, 3},
+			word:    big.NewInt(2),
+			prizeId: 2,
+		},
+		{
+			name:    "first word of the next slot",
+			weights: []int64{1, 0, 2, 3},
+			word:    big.NewInt(3),
+			prizeId: 3,
+		},
+		{
+			name:    "last slot",
+			weights: []int64{1, 0, 2, 3},
+			word:    big.NewInt(5),
+			prizeId: 3,
+		},
+		{
+			name:    "word wraps around the total",
+			weights: []int64{1, 0, 2, 3},
+			word:    big.NewInt(6),
+			prizeId: 0,
+		},
+		{
+			name:    "largest word",
+			weights: []int64{1, 0, 2, 3},
+			word:    maxWord,
+			prizeId: 3,
+		},
+		{
+			name:    "zero weight last slot",
+			weights: []int64{2, 0},
+			word:    big.NewInt(1),
+			prizeId: 0,
+		},
+		{
+			name:    "total above int64, first slot",
+			weights: []int64{math.MaxInt64, math.MaxInt64},
+			word:    big.NewInt(math.MaxInt64 - 1),
+			prizeId: 0,
+		},
+		{
+			name:    "total above int64, slot boundary",
+			weights: []int64{math.MaxInt64, math.MaxInt64},
+			word:    big.NewInt(math.MaxInt64),
+			prizeId: 1,
+		},
+		{
+			name:    "total above int64, last slot",
+			weights: []int64{math.MaxInt64, math.MaxInt64},
+			word:    lastOfTwo,
+			prizeId: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wheel, err := NewWeightedWheel(WheelConfig{Model: "weighted", Weights: test.weights})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			prizeIds, err := wheel.PrizeIds([]*big.Int{test.word})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(prizeIds) != 1 || prizeIds[0] != test.prizeId {
+				t.Errorf("got prize ids %v, want [%d]", prizeIds, test.prizeId)
+			}
+		})
+	}
+}
@@ -3,8 +3,16 @@ package main
 import (
 	"VRFChainlink/api"
 	"VRFChainlink/database"
+	"VRFChainlink/event"
+	"fmt"
 	"github.com/joho/godotenv"
+	"github.com/uptrace/bun"
+	"golang.org/x/sync/errgroup"
 	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
 )
 
 func main() {
@@ -17,19 +25,161 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = migrateDatabase(db, os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	err = database.MigrateToDb(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		err = verifyFulfillments(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "track" {
+		gin := api.NewGin(db)
+		trackers, err := trackContracts(db, gin)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		go gin.Run()
+		log.Fatal(trackers.Wait())
+	}
 	//
 	gin := api.NewGin(db)
 	gin.Run()
+}
+
+// migrateDatabase runs the migrate command: "up" applies the pending
+// migrations, "down" rolls back the last group applied and "status" prints
+// every migration with the group it was applied in.
+func migrateDatabase(db *bun.DB, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		return database.MigrateToDb(db)
+	case "down":
+		return database.RollbackMigrationToDb(db)
+	case "status":
+		migrations, err := database.GetMigrationsFromDb(db)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			status := "pending"
+			if migration.IsApplied() {
+				status = fmt.Sprintf("applied in group %d at %s", migration.GroupID, migration.MigratedAt.Format(time.RFC3339))
+			}
+			fmt.Println(migration.Name, migration.Comment, status)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown migrate command %q, use up, down or status", command)
+}
+
+// trackContracts starts a tracker for every configured contract: it backfills
+// the contract up to the last final block, then follows the chain head by
+// polling or, when TRACKING_MODE is "subscribe", through the websocket RPC of
+// the chain. The RPC pools are reported by the API. The returned group fails
+// with the first tracker that stops.
+func trackContracts(db *bun.DB, gin *api.GinEngine) (*errgroup.Group, error) {
+	workers := 1
+	if os.Getenv("BACKFILL_WORKERS") != "" {
+		var err error
+		workers, err = strconv.Atoi(os.Getenv("BACKFILL_WORKERS"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	chains, err := event.LoadChainConfig(os.Getenv("CHAINS"))
+	if err != nil {
+		return nil, err
+	}
+
+	trackers := new(errgroup.Group)
+	for _, chain := range chains {
+		chainTx, err := event.NewChainTracking(chain)
+		if err != nil {
+			return nil, err
+		}
+		chainTx.Cache = event.NewBlockCache(0, db)
+		gin.AddPool(chainTx.Pool)
+		go chainTx.Pool.RunHealthCheck()
+
+		for _, contract := range chain.Contracts {
+			contractTx, err := chainTx.ForContract(contract)
+			if err != nil {
+				return nil, err
+			}
+
+			ws := chain.Ws
+			fromBlock := big.NewInt(contract.FromBlock)
+			trackers.Go(func() error {
+				err := contractTx.Backfill(db, fromBlock, workers)
+				if err != nil {
+					return err
+				}
 
-	//trackingTx, err := event.NewEventTracking(os.Getenv("RPC"), os.Getenv("CONTRACT_ADDRESS"))
-	//if err != nil {
-	//	log.Fatal(err)
-	//}
+				go contractTx.RetryBlockError(db)
+				go contractTx.CheckPrizes(db)
+				go contractTx.WatchPending(db)
+				if os.Getenv("TRACKING_MODE") == "subscribe" && ws != "" {
+					return contractTx.SubscribeEventFromBlockNumber(db, ws, fromBlock)
+				}
+				return contractTx.GetEventFromBlockNumber(db, fromBlock)
+			})
+		}
+	}
+
+	return trackers, nil
+}
 
-	//fromBlock, err := strconv.ParseInt(os.Getenv("FROM_BLOCK"), 10, 64)
-	//if err != nil {
-	//	log.Fatal(err)
-	//}
+// verifyFulfillments checks the VRF proofs of the stored fulfillments of every
+// configured contract that have not been verified yet.
+func verifyFulfillments(db *bun.DB) error {
+	chains, err := event.LoadChainConfig(os.Getenv("CHAINS"))
+	if err != nil {
+		return err
+	}
+
+	for _, chain := range chains {
+		chainTx, err := event.NewChainTracking(chain)
+		if err != nil {
+			return err
+		}
+		chainTx.Cache = event.NewBlockCache(0, db)
+
+		for _, contract := range chain.Contracts {
+			contractTx, err := chainTx.ForContract(contract)
+			if err != nil {
+				return err
+			}
+
+			err = contractTx.VerifyFulfillments(db)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-	//trackingTx.GetEventFromBlockNumber(db, big.NewInt(fromBlock))
+	return nil
 }